@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+)
+
+var (
+	addName         string
+	addVersion      string
+	addPlatform     string
+	addLoader       string
+	addGameVersions []string
+)
+
+var addCmd = &cobra.Command{
+	Use:   "add <source>:<project>",
+	Short: "Add a plugin to the manifest",
+	Long: `Look up a plugin, add it to the manifest and resolve it into the lock file.
+
+The manifest is edited in place, keeping existing comments and ordering.
+
+Examples:
+  scaf add modrinth:luckperms --version '^5.4'
+  scaf add hangar:NEZNAMY/TAB --platform VELOCITY
+  scaf add url:https://example.com/plugin.jar --name example`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAdd,
+}
+
+func init() {
+	addCmd.Flags().StringVarP(&manifestFile, "manifest", "m", "plugins.yaml", "Path to manifest file")
+	addCmd.Flags().StringVarP(&lockFile, "lockfile", "l", "plugins.lock.yaml", "Path to lock file")
+	addCmd.Flags().StringVarP(&addName, "name", "n", "", "Manifest entry name (default: derived from project)")
+	addCmd.Flags().StringVar(&addVersion, "version", "latest", "Version constraint")
+	addCmd.Flags().StringVar(&addPlatform, "platform", "", "Hangar platform (e.g. VELOCITY, PAPER)")
+	addCmd.Flags().StringVar(&addLoader, "loader", "", "Modrinth loader (e.g. velocity, paper)")
+	addCmd.Flags().StringSliceVar(&addGameVersions, "game-version", nil, "Modrinth game versions to filter by")
+}
+
+func runAdd(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	source, project, ok := strings.Cut(args[0], ":")
+	if !ok || project == "" {
+		return fmt.Errorf("expected <source>:<project>, got %q", args[0])
+	}
+
+	plugin := &manifest.PluginConfig{
+		Source:       source,
+		Version:      addVersion,
+		Platform:     addPlatform,
+		Loader:       addLoader,
+		GameVersions: addGameVersions,
+	}
+	switch source {
+	case "url":
+		plugin.URL = project
+	case "s3":
+		bucket, key, ok := strings.Cut(project, "/")
+		if !ok {
+			return fmt.Errorf("expected s3:<bucket>/<key>, got %q", args[0])
+		}
+		plugin.Bucket = bucket
+		plugin.Key = key
+	default:
+		plugin.Project = project
+	}
+
+	name := addName
+	if name == "" {
+		name = strings.ToLower(strings.TrimSuffix(path.Base(project), ".jar"))
+	}
+
+	data, err := os.ReadFile(manifestFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("reading manifest: %w", err)
+	}
+
+	// Resolve first so typos in the project never reach the manifest, with
+	// the manifest's settings and the locked Paper version
+	m := &manifest.Manifest{}
+	if data != nil {
+		if m, err = manifest.Load(manifestFile); err != nil {
+			return err
+		}
+	}
+	var previous *manifest.Lockfile
+	if _, err := os.Stat(lockFile); err == nil {
+		if previous, err = manifest.LoadLockfile(lockFile); err != nil {
+			return err
+		}
+	}
+	res := newResolution(m, previous)
+	resolved, err := res.resolvePlugin(ctx, name, plugin)
+	if err != nil {
+		return err
+	}
+
+	updated, err := manifest.AddPlugin(data, name, plugin)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(manifestFile, updated, 0644); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "\nAdded %s to %s\n", name, manifestFile)
+
+	return updateLockfile(func(lf *manifest.Lockfile) error {
+		resolved.ResolvedAt = resolvedAt(lf)
+		lf.ResolvedAt = resolved.ResolvedAt
+		lf.Plugins[name] = resolved
+		if err := res.resolveDependencies(ctx, lf); err != nil {
+			return err
+		}
+		if problems := checkConflicts(lf); len(problems) > 0 {
+			return conflictError(problems)
+		}
+		return nil
+	})
+}
+
+// updateLockfile applies fn to the existing lock file and writes it back.
+// A missing lock file is left alone, since a partial lock would not match
+// the manifest.
+func updateLockfile(fn func(lf *manifest.Lockfile) error) error {
+	if _, err := os.Stat(lockFile); os.IsNotExist(err) {
+		fmt.Fprintf(os.Stderr, "%s does not exist, run 'scaf resolve' to create it\n", lockFile)
+		return nil
+	}
+
+	m, err := manifest.Load(manifestFile)
+	if err != nil {
+		return err
+	}
+	digest, err := m.Digest()
+	if err != nil {
+		return err
+	}
+
+	lf, err := manifest.LoadLockfile(lockFile)
+	if err != nil {
+		return err
+	}
+	if err := fn(lf); err != nil {
+		return err
+	}
+	setDigest(m, lf, digest)
+	if err := lf.Write(lockFile); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Updated %s\n", lockFile)
+	return nil
+}
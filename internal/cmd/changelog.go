@@ -1,126 +1,509 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/goccy/go-yaml"
 	"github.com/spf13/cobra"
 
 	"github.com/PrimCraft/scaf/internal/manifest"
+	"github.com/PrimCraft/scaf/internal/resolver"
+	"github.com/PrimCraft/scaf/internal/vcs"
+)
+
+var (
+	changelogFormat string
+	changelogNotes  bool
+	changelogFrom   string
+	changelogTo     string
 )
 
 var changelogCmd = &cobra.Command{
-	Use:   "changelog <old-lockfile> <new-lockfile>",
+	Use:   "changelog [<old-lockfile> <new-lockfile>]",
 	Short: "Generate changelog between two lock files",
-	Long:  `Compare two lock files and output the differences in markdown format.`,
-	Args:  cobra.ExactArgs(2),
-	RunE:  runChangelog,
+	Long: `Compare two lock files and output the differences, grouped into added,
+removed, upgraded and downgraded entries with the semver bump of each change.
+
+Lock files can be read from git instead of from disk, either with
+<rev>:<path> arguments (the path is relative to the repository root, as
+with "git show") or with --from/--to, which read the --lockfile path at the
+given revisions. Without --to, the lock file on disk is the new side.
+
+With --notes, the upstream release notes of every version between the old
+and new version are included: Modrinth version changelogs, Hangar version
+descriptions and PaperMC build commit messages. Added entries get the notes
+of their new version, or of every build of it up to the new one for
+PaperMC and Purpur. In markdown they are rendered as collapsible sections
+per entry.
+
+Output formats: markdown, text, json
+
+Examples:
+  scaf changelog old.lock.yaml plugins.lock.yaml
+  scaf changelog HEAD~1:plugins.lock.yaml HEAD:plugins.lock.yaml
+  scaf changelog --from origin/main --to HEAD`,
+	Args: cobra.MaximumNArgs(2),
+	RunE: runChangelog,
+}
+
+func init() {
+	changelogCmd.Flags().StringVarP(&changelogFormat, "format", "f", "markdown", "Output format (markdown, text, json)")
+	changelogCmd.Flags().BoolVar(&changelogNotes, "notes", false, "Include upstream release notes")
+	changelogCmd.Flags().StringVar(&changelogFrom, "from", "", "Git revision of the old lock file")
+	changelogCmd.Flags().StringVar(&changelogTo, "to", "", "Git revision of the new lock file (default: file on disk)")
+	changelogCmd.Flags().StringVarP(&lockFile, "lockfile", "l", "plugins.lock.yaml", "Path to lock file used with --from/--to")
+}
+
+// Change kinds, in the order they are rendered.
+const (
+	changeAdded      = "added"
+	changeRemoved    = "removed"
+	changeUpgraded   = "upgraded"
+	changeDowngraded = "downgraded"
+	changeChanged    = "changed"
+)
+
+var changeKinds = []string{changeAdded, changeRemoved, changeUpgraded, changeDowngraded, changeChanged}
+
+// change is a single difference between two lock files.
+type change struct {
+	Name      string `json:"name"`
+	Kind      string `json:"kind"`
+	From      string `json:"from,omitempty"`
+	To        string `json:"to,omitempty"`
+	Bump      string `json:"bump,omitempty"`
+	Source    string `json:"source,omitempty"`
+	OldSource string `json:"old_source,omitempty"`
+
+	Notes []resolver.ReleaseNote `json:"notes,omitempty"`
+
+	// component is the lock file key of a server component change, and
+	// pack the section of a datapack or resource pack change.
+	component string
+	pack      *packKind
 }
 
 func runChangelog(cmd *cobra.Command, args []string) error {
-	oldPath := args[0]
-	newPath := args[1]
+	switch changelogFormat {
+	case "markdown", "text", "json":
+	default:
+		return fmt.Errorf("unknown format %q", changelogFormat)
+	}
 
-	// Load old lockfile
-	oldData, err := os.ReadFile(oldPath)
+	oldRef, newRef, err := changelogRefs(args)
 	if err != nil {
-		return fmt.Errorf("reading old lock file: %w", err)
+		return err
 	}
 
-	var oldLock manifest.Lockfile
-	if err := yaml.Unmarshal(oldData, &oldLock); err != nil {
-		return fmt.Errorf("parsing old lock file: %w", err)
+	oldLock, err := loadLockfileRef(oldRef)
+	if err != nil {
+		return fmt.Errorf("old lock file: %w", err)
 	}
+	newLock, err := loadLockfileRef(newRef)
+	if err != nil {
+		return fmt.Errorf("new lock file: %w", err)
+	}
+
+	changes := diffLockfiles(oldLock, newLock)
 
-	// Load new lockfile
-	newData, err := os.ReadFile(newPath)
+	if changelogNotes {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		fetchNotes(ctx, resolver.NewRegistry(), oldLock, newLock, changes)
+	}
+
+	switch changelogFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if changes == nil {
+			changes = []change{}
+		}
+		return enc.Encode(changes)
+	case "text":
+		writeChangelogText(os.Stdout, changes)
+	default:
+		writeChangelogMarkdown(os.Stdout, changes)
+	}
+	return nil
+}
+
+// changelogRefs determines the old and new lock file references from the
+// arguments and the --from/--to flags.
+func changelogRefs(args []string) (string, string, error) {
+	if changelogFrom == "" {
+		if changelogTo != "" {
+			return "", "", fmt.Errorf("--to requires --from")
+		}
+		if len(args) != 2 {
+			return "", "", fmt.Errorf("expected two lock files, or --from")
+		}
+		return args[0], args[1], nil
+	}
+
+	if len(args) > 0 {
+		return "", "", fmt.Errorf("lock file arguments cannot be combined with --from")
+	}
+
+	// git revisions take paths relative to the repository root
+	path, err := vcs.RepoPath(lockFile)
 	if err != nil {
-		return fmt.Errorf("reading new lock file: %w", err)
+		return "", "", err
+	}
+
+	newRef := lockFile
+	if changelogTo != "" {
+		newRef = changelogTo + ":" + path
 	}
+	return changelogFrom + ":" + path, newRef, nil
+}
 
-	var newLock manifest.Lockfile
-	if err := yaml.Unmarshal(newData, &newLock); err != nil {
-		return fmt.Errorf("parsing new lock file: %w", err)
+// loadLockfileRef loads a lock file from a path, or from git if ref is not
+// an existing file and has the form <rev>:<path>.
+func loadLockfileRef(ref string) (*manifest.Lockfile, error) {
+	if _, err := os.Stat(ref); err == nil {
+		return manifest.LoadLockfile(ref)
 	}
 
-	// Compare and output changes
-	hasChanges := false
+	rev, path, ok := strings.Cut(ref, ":")
+	if !ok || rev == "" || path == "" {
+		return manifest.LoadLockfile(ref)
+	}
 
-	// Check Velocity
-	if oldLock.Velocity != nil || newLock.Velocity != nil {
-		oldVer := "N/A"
-		oldBuild := 0
-		newVer := "N/A"
-		newBuild := 0
+	data, err := vcs.ReadFile(rev, path)
+	if err != nil {
+		return nil, err
+	}
+	return manifest.ParseLockfile(data)
+}
 
-		if oldLock.Velocity != nil {
-			oldVer = oldLock.Velocity.Version
-			oldBuild = oldLock.Velocity.Build
+// diffLockfiles compares two lock files. Changes are sorted by kind, with
+// server components first, then plugins and packs in name order.
+func diffLockfiles(oldLock, newLock *manifest.Lockfile) []change {
+	var changes []change
+
+	components := make(map[string]bool)
+	for name := range oldLock.Components {
+		components[name] = true
+	}
+	for name := range newLock.Components {
+		components[name] = true
+	}
+	for _, name := range slices.Sorted(maps.Keys(components)) {
+		oldC, newC := oldLock.Components[name], newLock.Components[name]
+		label := name
+		if name != "" {
+			label = strings.ToUpper(name[:1]) + name[1:]
 		}
-		if newLock.Velocity != nil {
-			newVer = newLock.Velocity.Version
-			newBuild = newLock.Velocity.Build
+		switch {
+		case oldC == nil:
+			changes = append(changes, change{Name: label, Kind: changeAdded, To: componentVersion(newC), component: name})
+		case newC == nil:
+			changes = append(changes, change{Name: label, Kind: changeRemoved, From: componentVersion(oldC), component: name})
+		case componentVersion(oldC) != componentVersion(newC):
+			ch := versionChange(label, oldC.Version, newC.Version)
+			if oldC.Version == newC.Version {
+				// A new build, loader or installer for the same version
+				ch.Kind = changeUpgraded
+				switch {
+				case oldC.Build != newC.Build:
+					if newC.Build < oldC.Build {
+						ch.Kind = changeDowngraded
+					}
+				case oldC.Loader != newC.Loader:
+					ch.Kind = versionChange(label, oldC.Loader, newC.Loader).Kind
+				default:
+					ch.Kind = versionChange(label, oldC.Installer, newC.Installer).Kind
+				}
+				ch.Bump = componentBump(oldC, newC)
+			}
+			ch.From, ch.To = componentVersion(oldC), componentVersion(newC)
+			ch.component = name
+			changes = append(changes, ch)
+		}
+	}
+
+	names := make(map[string]bool)
+	for name := range oldLock.Plugins {
+		names[name] = true
+	}
+	for name := range newLock.Plugins {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	for _, name := range sorted {
+		oldPlugin, inOld := oldLock.Plugins[name]
+		newPlugin, inNew := newLock.Plugins[name]
+
+		switch {
+		case !inOld:
+			changes = append(changes, change{Name: name, Kind: changeAdded, To: newPlugin.Version, Source: pluginSource(newPlugin)})
+		case !inNew:
+			changes = append(changes, change{Name: name, Kind: changeRemoved, From: oldPlugin.Version, Source: pluginSource(oldPlugin)})
+		default:
+			oldSource, newSource := pluginSource(oldPlugin), pluginSource(newPlugin)
+			if oldPlugin.Version == newPlugin.Version && oldSource == newSource {
+				continue
+			}
+			ch := versionChange(name, oldPlugin.Version, newPlugin.Version)
+			ch.Source = newSource
+			if oldSource != newSource {
+				ch.OldSource = oldSource
+			}
+			changes = append(changes, ch)
 		}
+	}
 
-		if oldVer != newVer || oldBuild != newBuild {
-			oldStr := formatVersion(oldVer, oldBuild)
-			newStr := formatVersion(newVer, newBuild)
-			fmt.Printf("- **Velocity**: %s -> %s\n", oldStr, newStr)
-			hasChanges = true
+	for i, kind := range packKinds {
+		oldPacks, newPacks := kind.locked(oldLock), kind.locked(newLock)
+		packs := make(map[string]bool)
+		for name := range oldPacks {
+			packs[name] = true
+		}
+		for name := range newPacks {
+			packs[name] = true
+		}
+		for _, name := range slices.Sorted(maps.Keys(packs)) {
+			oldPack, newPack := oldPacks[name], newPacks[name]
+			var ch change
+			switch {
+			case oldPack == nil:
+				ch = change{Name: name, Kind: changeAdded, To: newPack.Version, Source: packSource(newPack)}
+			case newPack == nil:
+				ch = change{Name: name, Kind: changeRemoved, From: oldPack.Version, Source: packSource(oldPack)}
+			default:
+				oldSource, newSource := packSource(oldPack), packSource(newPack)
+				if oldPack.Version == newPack.Version && oldSource == newSource {
+					continue
+				}
+				ch = versionChange(name, oldPack.Version, newPack.Version)
+				ch.Source = newSource
+				if oldSource != newSource {
+					ch.OldSource = oldSource
+				}
+			}
+			ch.pack = &packKinds[i]
+			changes = append(changes, ch)
 		}
 	}
 
-	// Check Paper
-	if oldLock.Paper != nil || newLock.Paper != nil {
-		oldVer := "N/A"
-		oldBuild := 0
-		newVer := "N/A"
-		newBuild := 0
+	// Stable sort keeps components first and plugins and packs in name order
+	rank := make(map[string]int)
+	for i, kind := range changeKinds {
+		rank[kind] = i
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return rank[changes[i].Kind] < rank[changes[j].Kind]
+	})
+
+	return changes
+}
 
-		if oldLock.Paper != nil {
-			oldVer = oldLock.Paper.Version
-			oldBuild = oldLock.Paper.Build
+// fetchNotes attaches upstream release notes to upgrades and added
+// entries. Failures are
+// reported as warnings, so a changelog is produced even when a source is
+// unreachable.
+func fetchNotes(ctx context.Context, registry *resolver.Registry, oldLock, newLock *manifest.Lockfile, changes []change) {
+	for i := range changes {
+		ch := &changes[i]
+		added := ch.Kind == changeAdded
+		if (ch.Kind != changeUpgraded && !added) || ch.OldSource != "" {
+			continue
 		}
-		if newLock.Paper != nil {
-			newVer = newLock.Paper.Version
-			newBuild = newLock.Paper.Build
+
+		var cfg resolver.PluginConfig
+		var from, to *resolver.Result
+		switch {
+		case ch.component != "":
+			oldC, newC := oldLock.Components[ch.component], newLock.Components[ch.component]
+			if !added && (oldC.Source != newC.Source || oldC.Project != newC.Project) {
+				continue
+			}
+			cfg = resolver.PluginConfig{Source: newC.Source, Project: newC.Project}
+			if !added {
+				from = &resolver.Result{Version: oldC.Version, Build: oldC.Build}
+			}
+			to = &resolver.Result{Version: newC.Version, Build: newC.Build}
+		case ch.pack != nil:
+			p := ch.pack.locked(newLock)[ch.Name]
+			cfg = resolver.PluginConfig{
+				Source:       p.Source,
+				Project:      p.Project,
+				Loader:       ch.pack.loader,
+				GameVersions: p.GameVersions,
+			}
+			if !added {
+				from = &resolver.Result{Version: ch.pack.locked(oldLock)[ch.Name].Version}
+			}
+			to = &resolver.Result{Version: p.Version}
+		default:
+			p := newLock.Plugins[ch.Name]
+			cfg = resolver.PluginConfig{
+				Source:       p.Source,
+				Project:      p.Project,
+				Platform:     p.Platform,
+				Loader:       p.Loader,
+				GameVersions: p.GameVersions,
+			}
+			if !added {
+				from = &resolver.Result{Version: oldLock.Plugins[ch.Name].Version}
+			}
+			to = &resolver.Result{Version: p.Version}
 		}
 
-		if oldVer != newVer || oldBuild != newBuild {
-			oldStr := formatVersion(oldVer, oldBuild)
-			newStr := formatVersion(newVer, newBuild)
-			fmt.Printf("- **Paper**: %s -> %s\n", oldStr, newStr)
-			hasChanges = true
+		fmt.Fprintf(os.Stderr, "Fetching release notes for %s...\n", ch.Name)
+		notes, err := registry.Notes(ctx, cfg.Source, cfg, from, to)
+		if err != nil {
+			if !errors.Is(err, resolver.ErrUnsupported) {
+				fmt.Fprintf(os.Stderr, "  warning: %v\n", err)
+			}
+			continue
 		}
+		ch.Notes = notes
 	}
+}
 
-	// Check for updated plugins
-	for name, newPlugin := range newLock.Plugins {
-		oldPlugin, existed := oldLock.Plugins[name]
+// versionChange classifies a version change as an upgrade or downgrade.
+func versionChange(name, from, to string) change {
+	ch := change{Name: name, Kind: changeChanged, From: from, To: to}
+	if from == to {
+		return ch
+	}
 
-		if !existed {
-			fmt.Printf("- **%s**: added (%s)\n", name, newPlugin.Version)
-			hasChanges = true
-		} else if oldPlugin.Version != newPlugin.Version {
-			fmt.Printf("- **%s**: %s -> %s\n", name, oldPlugin.Version, newPlugin.Version)
-			hasChanges = true
+	if cmp, ok := resolver.CompareVersions(from, to); ok {
+		switch {
+		case cmp < 0:
+			ch.Kind = changeUpgraded
+		case cmp > 0:
+			ch.Kind = changeDowngraded
 		}
+		ch.Bump = resolver.Bump(from, to)
+	}
+	return ch
+}
+
+func pluginSource(p *manifest.ResolvedPlugin) string {
+	if p.Project == "" {
+		return p.Source
+	}
+	return p.Source + ":" + p.Project
+}
+
+func packSource(p *manifest.ResolvedPack) string {
+	return p.Source + ":" + p.Project
+}
+
+func writeChangelogMarkdown(w io.Writer, changes []change) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes detected")
+		return
 	}
 
-	// Check for removed plugins
-	for name, oldPlugin := range oldLock.Plugins {
-		if _, exists := newLock.Plugins[name]; !exists {
-			fmt.Printf("- **%s**: removed (was %s)\n", name, oldPlugin.Version)
-			hasChanges = true
+	kind := ""
+	for _, ch := range changes {
+		if ch.Kind != kind {
+			if kind != "" {
+				fmt.Fprintln(w)
+			}
+			kind = ch.Kind
+			fmt.Fprintf(w, "### %s\n\n", title(kind))
 		}
+		fmt.Fprintf(w, "- **%s**: %s\n", ch.Name, describeChange(ch))
 	}
 
-	if !hasChanges {
-		fmt.Println("No changes detected")
+	first := true
+	for _, ch := range changes {
+		if len(ch.Notes) == 0 {
+			continue
+		}
+		if first {
+			fmt.Fprint(w, "\n### Release notes\n")
+			first = false
+		}
+		fmt.Fprintf(w, "\n<details>\n<summary><b>%s</b> %s</summary>\n", ch.Name, describeChange(ch))
+		for _, note := range ch.Notes {
+			fmt.Fprintf(w, "\n#### %s\n\n%s\n", note.Version, noteText(note))
+		}
+		fmt.Fprint(w, "\n</details>\n")
 	}
+}
 
-	return nil
+func writeChangelogText(w io.Writer, changes []change) {
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes detected")
+		return
+	}
+
+	kind := ""
+	for _, ch := range changes {
+		if ch.Kind != kind {
+			kind = ch.Kind
+			fmt.Fprintf(w, "%s:\n", title(kind))
+		}
+		fmt.Fprintf(w, "  %s: %s\n", ch.Name, describeChange(ch))
+		for _, note := range ch.Notes {
+			fmt.Fprintf(w, "    %s:\n", note.Version)
+			for _, line := range strings.Split(noteText(note), "\n") {
+				fmt.Fprintf(w, "      %s\n", line)
+			}
+		}
+	}
+}
+
+func noteText(note resolver.ReleaseNote) string {
+	text := strings.TrimSpace(note.Text)
+	if text == "" {
+		return "_No release notes._"
+	}
+	return text
+}
+
+// describeChange renders the details of a change after the entry name.
+func describeChange(ch change) string {
+	var desc string
+	switch {
+	case ch.Kind == changeAdded:
+		desc = ch.To
+	case ch.Kind == changeRemoved:
+		desc = "was " + ch.From
+	case ch.From == ch.To:
+		desc = ch.To
+	default:
+		desc = ch.From + " -> " + ch.To
+	}
+
+	var notes []string
+	if ch.Bump != "" {
+		notes = append(notes, ch.Bump)
+	}
+	switch {
+	case ch.OldSource != "":
+		notes = append(notes, "source "+ch.OldSource+" -> "+ch.Source)
+	case ch.Kind == changeAdded && ch.Source != "":
+		notes = append(notes, ch.Source)
+	}
+	if len(notes) > 0 {
+		desc += " (" + strings.Join(notes, ", ") + ")"
+	}
+	return desc
+}
+
+func title(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
 }
 
 func formatVersion(version string, build int) string {
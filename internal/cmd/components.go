@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+	"github.com/PrimCraft/scaf/internal/resolver"
+)
+
+// componentPlatform returns the plugin platform a component project runs,
+// or "" if it is not known to load plugins.
+func componentPlatform(project string) string {
+	switch project {
+	case "paper", "folia", "purpur":
+		return "paper"
+	case "velocity":
+		return "velocity"
+	case "waterfall", "bungeecord":
+		return "bungee"
+	case "fabric", "neoforge", "forge":
+		return project
+	}
+	return ""
+}
+
+// paperServer returns the locked component that runs Paper plugins, or nil
+// if there is none. With several, the first by name wins.
+func paperServer(lf *manifest.Lockfile) *manifest.ResolvedComponent {
+	for _, name := range slices.Sorted(maps.Keys(lf.Components)) {
+		if c := lf.Components[name]; componentPlatform(c.Project) == "paper" {
+			return c
+		}
+	}
+	return nil
+}
+
+// componentLabel names a component for output, with its project if that
+// differs from the name.
+func componentLabel(name, project string) string {
+	if project == "" || project == name {
+		return name
+	}
+	return name + " (" + project + ")"
+}
+
+// componentVersion formats the locked version of a component, with the
+// loader and installer of modded servers.
+func componentVersion(c *manifest.ResolvedComponent) string {
+	switch {
+	case c.Installer != "":
+		return fmt.Sprintf("%s (loader %s, installer %s)", c.Version, c.Loader, c.Installer)
+	case c.Loader != "":
+		return fmt.Sprintf("%s (loader %s)", c.Version, c.Loader)
+	}
+	return formatVersion(c.Version, c.Build)
+}
+
+// componentBump describes the change from one locked component to another:
+// the bump of the game version, or failing that "build", "loader" or
+// "installer".
+func componentBump(from, to *manifest.ResolvedComponent) string {
+	switch {
+	case from.Version != to.Version:
+		return resolver.Bump(from.Version, to.Version)
+	case from.Build != to.Build:
+		return "build"
+	case from.Loader != to.Loader:
+		return "loader"
+	case from.Installer != to.Installer:
+		return "installer"
+	}
+	return ""
+}
+
+// componentStale reports whether a locked component no longer matches its
+// manifest entry.
+func componentStale(c *manifest.ComponentConfig, locked *manifest.ResolvedComponent) bool {
+	if c.Source != locked.Source || c.Project != locked.Project || c.Path() != locked.File {
+		return true
+	}
+	// Lock files written before constraints were recorded have none
+	if locked.Constraint != "" && locked.Constraint != c.Version {
+		return true
+	}
+	if !resolver.Satisfies(locked.Loader, c.Loader) || !resolver.Satisfies(locked.Installer, c.Installer) {
+		return true
+	}
+	return !resolver.Satisfies(locked.Version, c.Version)
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+)
+
+// checkConflicts returns the entries of lf that would clash on a server:
+// entries resolving to the same project or the same file, and, for jars
+// with a recorded declaration, plugins declaring or providing the same
+// name and plugins that load before a plugin they depend on.
+func checkConflicts(lf *manifest.Lockfile) []string {
+	var problems []string
+	names := sortedPlugins(lf)
+
+	// same groups entries by a key, reporting keys shared by several
+	same := func(what string, key func(name string, p *manifest.ResolvedPlugin) string) {
+		groups := make(map[string][]string)
+		var keys []string
+		for _, name := range names {
+			k := key(name, lf.Plugins[name])
+			if k == "" {
+				continue
+			}
+			if groups[k] == nil {
+				keys = append(keys, k)
+			}
+			groups[k] = append(groups[k], name)
+		}
+		for _, k := range keys {
+			if len(groups[k]) > 1 {
+				problems = append(problems, fmt.Sprintf("%s %s", strings.Join(groups[k], ", "), fmt.Sprintf(what, k)))
+			}
+		}
+	}
+
+	same("resolve to the same project %s", func(_ string, p *manifest.ResolvedPlugin) string {
+		if p.Project == "" {
+			return ""
+		}
+		key := p.Source + ":" + strings.ToLower(p.Project)
+		if target := p.Platform + p.Loader; target != "" {
+			key += " for " + strings.ToLower(target)
+		}
+		return key
+	})
+	same("resolve to the same file (sha256 %s)", func(_ string, p *manifest.ResolvedPlugin) string { return p.SHA256 })
+	same("resolve to the same file (sha512 %s)", func(_ string, p *manifest.ResolvedPlugin) string {
+		if p.SHA256 != "" {
+			return ""
+		}
+		return p.SHA512
+	})
+	same("download the same URL %s", func(_ string, p *manifest.ResolvedPlugin) string { return p.URL })
+	same("are downloaded to the same path %s", func(name string, p *manifest.ResolvedPlugin) string {
+		return strings.ToLower(path.Clean(pluginFile(name, p)))
+	})
+	same("declare the same plugin name %s", func(_ string, p *manifest.ResolvedPlugin) string {
+		if p.Declared == nil {
+			return ""
+		}
+		return fmt.Sprintf("%s (%s)", strings.ToLower(p.Declared.Name), platformGroup(p.Declared.Platform))
+	})
+
+	// A provided name must not be another plugin's name or provided twice
+	providers := make(map[string]string)
+	for _, name := range names {
+		if d := lf.Plugins[name].Declared; d != nil {
+			providers[platformGroup(d.Platform)+":"+strings.ToLower(d.Name)] = name
+		}
+	}
+	for _, name := range names {
+		d := lf.Plugins[name].Declared
+		if d == nil {
+			continue
+		}
+		for _, provided := range d.Provides {
+			key := platformGroup(d.Platform) + ":" + strings.ToLower(provided)
+			if other, ok := providers[key]; ok && other != name {
+				problems = append(problems, fmt.Sprintf("%s provides %s, which %s also declares or provides", name, provided, other))
+				continue
+			}
+			providers[key] = name
+		}
+
+		for _, before := range d.LoadBefore {
+			if slices.ContainsFunc(d.Depend, func(dep string) bool { return strings.EqualFold(dep, before) }) {
+				problems = append(problems, fmt.Sprintf("%s depends on %s but is declared to load before it", name, before))
+			}
+		}
+	}
+
+	return problems
+}
+
+// platformGroup maps descriptor platforms that load on the same server to
+// one name.
+func platformGroup(platform string) string {
+	if platform == "bukkit" {
+		return "paper"
+	}
+	return platform
+}
+
+// conflictError reports problems found by checkConflicts.
+func conflictError(problems []string) error {
+	return fmt.Errorf("conflicting plugins:\n  - %s", strings.Join(problems, "\n  - "))
+}
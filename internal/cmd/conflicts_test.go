@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+)
+
+func TestCheckConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		lock string
+		want []string
+	}{
+		{
+			name: "version 1",
+			lock: `resolved_at: 2024-05-01T10:00:00Z
+velocity:
+  version: 3.3.0-SNAPSHOT
+  build: 400
+  url: https://cdn.example/velocity.jar
+plugins:
+  luckperms:
+    source: modrinth
+    project: luckperms
+    version: 5.4.0
+    url: https://cdn.example/luckperms.jar
+    resolved_at: 2024-05-01T10:00:00Z
+  tab:
+    source: modrinth
+    project: tab
+    version: 4.1.0
+    url: https://cdn.example/tab.jar
+    resolved_at: 2024-05-01T10:00:00Z
+`,
+		},
+		{
+			name: "version 2",
+			lock: `lockfile_version: 2
+paper:
+  flavor: purpur
+  version: 1.21.4
+  build: 2416
+  url: https://cdn.example/purpur.jar
+plugins:
+  luckperms:
+    source: modrinth
+    project: luckperms
+    version: 5.4.0
+    url: https://cdn.example/luckperms.jar
+  tab:
+    source: hangar
+    project: NEZNAMY/TAB
+    version: 4.1.0
+    url: https://cdn.example/tab.jar
+`,
+		},
+		{
+			name: "version 3 plugins without file",
+			lock: `lockfile_version: 3
+plugins:
+  luckperms:
+    source: modrinth
+    project: luckperms
+    version: 5.4.0
+    url: https://cdn.example/luckperms.jar
+  tab:
+    source: modrinth
+    project: tab
+    version: 4.1.0
+    url: https://cdn.example/tab.jar
+`,
+		},
+		{
+			name: "file matching the default of another entry",
+			lock: `lockfile_version: 4
+plugins:
+  luckperms:
+    source: modrinth
+    project: luckperms
+    version: 5.4.0
+    url: https://cdn.example/luckperms.jar
+  tab:
+    source: modrinth
+    project: tab
+    version: 4.1.0
+    url: https://cdn.example/tab.jar
+    file: LuckPerms.jar
+`,
+			want: []string{"luckperms, tab are downloaded to the same path luckperms.jar"},
+		},
+		{
+			name: "same file",
+			lock: `lockfile_version: 4
+plugins:
+  luckperms:
+    source: modrinth
+    project: luckperms
+    version: 5.4.0
+    url: https://cdn.example/luckperms.jar
+    file: plugins/lp.jar
+  tab:
+    source: modrinth
+    project: tab
+    version: 4.1.0
+    url: https://cdn.example/tab.jar
+    file: plugins/./lp.jar
+`,
+			want: []string{"luckperms, tab are downloaded to the same path plugins/lp.jar"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lf, err := manifest.ParseLockfile([]byte(tt.lock))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := checkConflicts(lf); !slices.Equal(got, tt.want) {
+				t.Errorf("checkConflicts() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
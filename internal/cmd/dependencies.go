@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+	"github.com/PrimCraft/scaf/internal/resolver"
+)
+
+// dependency is a dependency declared by a resolved entry.
+type dependency struct {
+	resolver.Dependency
+
+	// parent is the name of the entry that declared it, from its lock entry
+	// and cfg the configuration it was resolved with.
+	parent string
+	from   *manifest.ResolvedPlugin
+	cfg    resolver.PluginConfig
+}
+
+// resolveDependencies adds the required dependencies of the entries
+// resolved so far to lf as transitive entries, recursively. Required
+// dependencies that cannot be added and incompatible plugins that are
+// locked are reported together as one error; missing optional
+// dependencies are only warned about.
+func (r *resolution) resolveDependencies(ctx context.Context, lf *manifest.Lockfile) error {
+	var checks []dependency
+	var problems []string
+	for len(r.pending) > 0 {
+		dep := r.pending[0]
+		r.pending = r.pending[1:]
+		if dep.Kind != resolver.DependencyRequired {
+			checks = append(checks, dep)
+			continue
+		}
+
+		if name := findDependency(lf, dep.Dependency); name != "" {
+			if locked := lf.Plugins[name]; locked.Transitive && !slices.Contains(locked.RequiredBy, dep.parent) {
+				locked.RequiredBy = append(locked.RequiredBy, dep.parent)
+				sort.Strings(locked.RequiredBy)
+			}
+			continue
+		}
+		if dep.Source == "" {
+			problems = append(problems, fmt.Sprintf("%s requires %s, which is not hosted on a supported source (%s); add it to the manifest",
+				dep.parent, dep.Project, orDash(dep.URL)))
+			continue
+		}
+
+		name := dependencyName(dep.Dependency)
+		if other, ok := lf.Plugins[name]; ok {
+			problems = append(problems, fmt.Sprintf("%s requires %s, but %s is the entry for %s; add it to the manifest under another name",
+				dep.parent, dependencyLabel(dep.Dependency), name, other.Source+":"+other.Project))
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s requires %s\n", dep.parent, dependencyLabel(dep.Dependency))
+		entry, err := r.resolve(ctx, name, dependencyConfig(dep), manifest.Policy{})
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s requires %s: %v", dep.parent, dependencyLabel(dep.Dependency), err))
+			continue
+		}
+		entry.Constraint = dep.Version
+		entry.Transitive = true
+		entry.RequiredBy = []string{dep.parent}
+		lf.Plugins[name] = entry
+	}
+
+	for _, dep := range checks {
+		name := findDependency(lf, dep.Dependency)
+		switch {
+		case dep.Kind == resolver.DependencyIncompatible && name != "":
+			problems = append(problems, fmt.Sprintf("%s is incompatible with %s", dep.parent, name))
+		case dep.Kind == resolver.DependencyOptional && name == "":
+			fmt.Fprintf(os.Stderr, "Warning: %s has an optional dependency on %s, which is not installed\n",
+				dep.parent, dependencyLabel(dep.Dependency))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("unsatisfied dependencies:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return nil
+}
+
+// dependencyLabel describes a dependency for messages.
+func dependencyLabel(dep resolver.Dependency) string {
+	if dep.Source == "" {
+		return dep.Project
+	}
+	return dep.Source + ":" + dep.Project
+}
+
+// dependencyConfig returns the resolver input for a dependency. It is
+// resolved for the same platform, loader and game versions as its parent.
+func dependencyConfig(dep dependency) resolver.PluginConfig {
+	version := dep.Version
+	if version == "" {
+		version = "latest"
+	}
+	return resolver.PluginConfig{
+		Source:       dep.Source,
+		Project:      dep.Project,
+		Version:      version,
+		Platform:     dep.from.Platform,
+		Loader:       dep.from.Loader,
+		GameVersions: dep.cfg.GameVersions,
+	}
+}
+
+// transitiveConfig returns the resolver input for re-resolving a locked
+// transitive entry.
+func transitiveConfig(locked *manifest.ResolvedPlugin) resolver.PluginConfig {
+	version := locked.Constraint
+	if version == "" {
+		version = "latest"
+	}
+	return resolver.PluginConfig{
+		Source:       locked.Source,
+		Project:      locked.Project,
+		Version:      version,
+		Platform:     locked.Platform,
+		Loader:       locked.Loader,
+		GameVersions: locked.GameVersions,
+	}
+}
+
+// findDependency returns the name of the lock file entry for the same
+// source and project as dep, including the owner of Hangar projects.
+func findDependency(lf *manifest.Lockfile, dep resolver.Dependency) string {
+	for name, p := range lf.Plugins {
+		if p.Source == dep.Source && strings.EqualFold(p.Project, dep.Project) {
+			return name
+		}
+	}
+	return ""
+}
+
+// dependencyName is the lock file entry name for a transitive dependency.
+func dependencyName(dep resolver.Dependency) string {
+	return strings.ToLower(path.Base(dep.Project))
+}
+
+// dropRequirer removes name from the required_by lists of lf.
+func dropRequirer(lf *manifest.Lockfile, name string) {
+	for _, p := range lf.Plugins {
+		p.RequiredBy = slices.DeleteFunc(p.RequiredBy, func(n string) bool { return n == name })
+	}
+}
+
+// pruneTransitive removes transitive entries that nothing requires anymore.
+func pruneTransitive(lf *manifest.Lockfile) {
+	for {
+		var orphans []string
+		for name, p := range lf.Plugins {
+			if p.Transitive && !required(lf, p) {
+				orphans = append(orphans, name)
+			}
+		}
+		if len(orphans) == 0 {
+			return
+		}
+		sort.Strings(orphans)
+		for _, name := range orphans {
+			fmt.Fprintf(os.Stderr, "Removing %s (no longer required)\n", name)
+			delete(lf.Plugins, name)
+			dropRequirer(lf, name)
+		}
+	}
+}
+
+// required reports whether any entry listed in p's required_by is locked.
+func required(lf *manifest.Lockfile, p *manifest.ResolvedPlugin) bool {
+	for _, parent := range p.RequiredBy {
+		if _, ok := lf.Plugins[parent]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -2,38 +2,74 @@ package cmd
 
 import (
 	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha512"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"maps"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/goccy/go-yaml"
 	"github.com/spf13/cobra"
 
 	"github.com/PrimCraft/scaf/internal/manifest"
 )
 
 var (
-	lockFile  string
-	outputDir string
-	parallel  int
+	lockFile       string
+	outputDir      string
+	serverDir      string
+	parallel       int
+	downloadRecord bool
 )
 
 var downloadCmd = &cobra.Command{
 	Use:   "download",
 	Short: "Download plugins from lock file",
-	Long:  `Download all plugins specified in the lock file to the output directory.`,
-	RunE:  runDownload,
+	Long: `Download all plugins specified in the lock file to the output directory.
+
+Each jar is saved at the path recorded in the lock file, relative to the
+output directory: <name>.jar for plugins unless settings.filename sets a
+template, inside settings.dest or the entry's dest, and <project>.jar for
+server components. Paths outside the output directory are refused.
+
+Datapacks and resource packs are saved relative to the server directory
+(--server-dir, the current directory by default) rather than the output
+directory: datapacks to the datapacks folder of settings.world ("world" by
+default) and resource packs to resourcepacks, unless the entry sets dest.
+They and server components are checked against the hashes in the lock
+file. NeoForge and Forge are locked as their installer, which has to be
+run to set up the server.
+
+Each plugin jar is inspected after download. The command fails if the jar
+has no plugin.yml, paper-plugin.yml, bungee.yml, velocity-plugin.json or
+fabric.mod.json for the platform it was resolved for, or if the version it
+declares disagrees with the locked version. With --record, the declared
+name, version, main class, api-version and required dependencies are
+written to the lock file.
+
+Paper plugins are then checked against the locked Paper version: the
+command fails if a plugin's api-version is newer than Paper, or if a
+plugin requires another plugin that is not in the lock file. It also fails
+if two jars declare or provide the same plugin name, or a plugin loads
+before a plugin it depends on.`,
+	RunE: runDownload,
 }
 
 func init() {
 	downloadCmd.Flags().StringVarP(&lockFile, "lockfile", "l", "plugins.lock.yaml", "Path to lock file")
 	downloadCmd.Flags().StringVarP(&outputDir, "output", "o", "./plugins", "Output directory")
+	downloadCmd.Flags().StringVar(&serverDir, "server-dir", ".", "Server directory datapacks and resource packs are downloaded to")
 	downloadCmd.Flags().IntVarP(&parallel, "parallel", "p", 4, "Number of parallel downloads")
+	downloadCmd.Flags().BoolVar(&downloadRecord, "record", false, "Record what each jar declares in the lock file")
 }
 
 func runDownload(cmd *cobra.Command, args []string) error {
@@ -41,14 +77,9 @@ func runDownload(cmd *cobra.Command, args []string) error {
 	defer cancel()
 
 	// Load lockfile
-	data, err := os.ReadFile(lockFile)
+	lf, err := manifest.LoadLockfile(lockFile)
 	if err != nil {
-		return fmt.Errorf("reading lock file: %w", err)
-	}
-
-	var lf manifest.Lockfile
-	if err := yaml.Unmarshal(data, &lf); err != nil {
-		return fmt.Errorf("parsing lock file: %w", err)
+		return err
 	}
 
 	// Create output directory
@@ -58,32 +89,40 @@ func runDownload(cmd *cobra.Command, args []string) error {
 
 	client := &http.Client{Timeout: 5 * time.Minute}
 
-	// Download Velocity if present
-	if lf.Velocity != nil {
-		fmt.Fprintf(os.Stderr, "Downloading Velocity %s...\n", lf.Velocity.Version)
-		dest := filepath.Join(outputDir, "velocity.jar")
-		if err := downloadHTTP(ctx, client, lf.Velocity.URL, dest); err != nil {
-			return fmt.Errorf("downloading velocity: %w", err)
+	// Download server components
+	for _, name := range slices.Sorted(maps.Keys(lf.Components)) {
+		c := lf.Components[name]
+		fmt.Fprintf(os.Stderr, "Downloading %s %s...\n", componentLabel(name, c.Project), c.Version)
+		dest, err := componentPath(outputDir, c)
+		if err != nil {
+			return fmt.Errorf("downloading %s: %w", name, err)
 		}
-		fmt.Fprintf(os.Stderr, "  -> %s\n", dest)
-	}
-
-	// Download Paper if present
-	if lf.Paper != nil {
-		fmt.Fprintf(os.Stderr, "Downloading Paper %s...\n", lf.Paper.Version)
-		dest := filepath.Join(outputDir, "paper.jar")
-		if err := downloadHTTP(ctx, client, lf.Paper.URL, dest); err != nil {
-			return fmt.Errorf("downloading paper: %w", err)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("creating directory for %s: %w", name, err)
+		}
+		if err := downloadHTTP(ctx, client, c.URL, dest); err != nil {
+			return fmt.Errorf("downloading %s: %w", name, err)
+		}
+		if err := checkComponent(dest, c); err != nil {
+			return fmt.Errorf("downloading %s: %w", name, err)
 		}
 		fmt.Fprintf(os.Stderr, "  -> %s\n", dest)
+		if c.Source == "neoforge" || c.Source == "forge" {
+			fmt.Fprintf(os.Stderr, "  run 'java -jar %s --installServer' to install the server\n", c.File)
+		}
 	}
 
 	// Download plugins
 	for name, plugin := range lf.Plugins {
 		fmt.Fprintf(os.Stderr, "Downloading %s %s...\n", name, plugin.Version)
-		dest := filepath.Join(outputDir, name+".jar")
+		dest, err := jarPath(outputDir, name, plugin)
+		if err != nil {
+			return fmt.Errorf("downloading %s: %w", name, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			return fmt.Errorf("creating directory for %s: %w", name, err)
+		}
 
-		var err error
 		if plugin.S3URI != "" {
 			err = downloadS3(ctx, plugin.S3URI, dest)
 		} else if plugin.URL != "" {
@@ -96,12 +135,112 @@ func runDownload(cmd *cobra.Command, args []string) error {
 			return fmt.Errorf("downloading %s: %w", name, err)
 		}
 		fmt.Fprintf(os.Stderr, "  -> %s\n", dest)
+
+		declared, err := inspectJar(lf, plugin, dest)
+		if err != nil {
+			// The server would load it on its next start
+			if rmErr := os.Remove(dest); rmErr != nil {
+				fmt.Fprintf(os.Stderr, "  warning: could not remove %s: %v\n", dest, rmErr)
+			}
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if declared != nil {
+			fmt.Fprintf(os.Stderr, "  %s %s (%s)\n", declared.Name, declared.Version, declared.Platform)
+		}
+		plugin.Declared = declared
+	}
+
+	// Download datapacks and resource packs
+	for _, kind := range packKinds {
+		packs := kind.locked(lf)
+		for _, name := range slices.Sorted(maps.Keys(packs)) {
+			if err := downloadPack(ctx, client, serverDir, name, packs[name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	problems := append(checkConflicts(lf), checkCompatibility(lf)...)
+	if len(problems) > 0 {
+		fmt.Fprintln(os.Stderr, "\nPlugins will not load:")
+		for _, p := range problems {
+			fmt.Fprintf(os.Stderr, "  - %s\n", p)
+		}
+		return fmt.Errorf("%d plugin problems", len(problems))
+	}
+
+	if downloadRecord {
+		if err := lf.Write(lockFile); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Recorded declared plugins in %s\n", lockFile)
 	}
 
 	fmt.Fprintf(os.Stderr, "\nDownloaded to %s\n", outputDir)
 	return nil
 }
 
+// pluginFile returns where download puts the jar of a plugin, relative to
+// the output directory.
+func pluginFile(name string, p *manifest.ResolvedPlugin) string {
+	if p.File == "" {
+		return name + ".jar"
+	}
+	return p.File
+}
+
+// jarPath returns where download puts the jar of a lock file entry.
+func jarPath(dir, name string, p *manifest.ResolvedPlugin) (string, error) {
+	return lockedPath(dir, pluginFile(name, p))
+}
+
+// componentPath returns where download puts the jar of a server component.
+func componentPath(dir string, c *manifest.ResolvedComponent) (string, error) {
+	return lockedPath(dir, c.File)
+}
+
+// lockedPath joins a path from the lock file to dir. The lock file may have
+// been edited, so paths that leave dir are refused.
+func lockedPath(dir, file string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(file)) {
+		return "", fmt.Errorf("path %q is outside %s", file, dir)
+	}
+	return filepath.Join(dir, filepath.FromSlash(file)), nil
+}
+
+// componentHashed reports whether the lock file records a hash for c.
+func componentHashed(c *manifest.ResolvedComponent) bool {
+	return c.MD5 != "" || c.SHA1 != ""
+}
+
+// checkComponent compares the file at path with the hashes locked for c.
+func checkComponent(path string, c *manifest.ResolvedComponent) error {
+	return checkHashes(path, c.MD5, c.SHA1, "")
+}
+
+// checkHashes compares the file at path with the expected hex digests.
+// Empty digests are not checked.
+func checkHashes(path, md5Hex, sha1Hex, sha512Hex string) error {
+	if md5Hex == "" && sha1Hex == "" && sha512Hex == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	md5sum, sha1sum, sha512sum := md5.Sum(data), sha1.Sum(data), sha512.Sum512(data)
+	for _, h := range []struct{ name, expected, actual string }{
+		{"md5", md5Hex, hex.EncodeToString(md5sum[:])},
+		{"sha1", sha1Hex, hex.EncodeToString(sha1sum[:])},
+		{"sha512", sha512Hex, hex.EncodeToString(sha512sum[:])},
+	} {
+		if h.expected != "" && !strings.EqualFold(h.actual, h.expected) {
+			return fmt.Errorf("%s mismatch: expected %s, got %s", h.name, h.expected, h.actual)
+		}
+	}
+	return nil
+}
+
 func downloadHTTP(ctx context.Context, client *http.Client, url, dest string) (err error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/PrimCraft/scaf/internal/jar"
+	"github.com/PrimCraft/scaf/internal/manifest"
+)
+
+// pluginsDir is the directory tree and why read downloaded jars from.
+var pluginsDir string
+
+// depGraph is the dependency graph of the plugins in a lock file. Edges
+// come from the lock file's required_by lists and, for downloaded jars,
+// from their plugin descriptors.
+type depGraph struct {
+	lf *manifest.Lockfile
+
+	// edges maps an entry name to the plugins it depends on. Targets are
+	// lock file entry names, or lowercased descriptor names for plugins
+	// that are not locked.
+	edges map[string][]depEdge
+}
+
+// depEdge is a dependency on another plugin.
+type depEdge struct {
+	to      string
+	soft    bool   // only loaded after to if present
+	via     string // where the dependency was declared
+	missing bool   // to is not in the lock file
+}
+
+// buildGraph builds the dependency graph of lf, reading descriptors of the
+// jars in dir. Entries whose jar has not been downloaded only get the
+// edges recorded in the lock file.
+func buildGraph(lf *manifest.Lockfile, dir string) *depGraph {
+	g := &depGraph{lf: lf, edges: make(map[string][]depEdge)}
+
+	for name, p := range lf.Plugins {
+		for _, parent := range p.RequiredBy {
+			g.add(parent, depEdge{to: name, via: "lock file"})
+		}
+	}
+
+	// Descriptors name plugins by their declared name, not the entry name
+	descriptors := make(map[string]*jar.Descriptor)
+	byPluginName := make(map[string]string)
+	for name, p := range lf.Plugins {
+		byPluginName[strings.ToLower(name)] = name
+		path, err := jarPath(dir, name, p)
+		if err != nil {
+			continue
+		}
+		info, err := jar.Inspect(path)
+		if err != nil {
+			continue
+		}
+		if d := info.Primary(); d != nil {
+			descriptors[name] = d
+			byPluginName[strings.ToLower(d.Name)] = name
+			// Velocity dependencies refer to the id
+			if d.ID != "" {
+				byPluginName[strings.ToLower(d.ID)] = name
+			}
+		}
+	}
+	if len(descriptors) == 0 && len(lf.Plugins) > 0 {
+		fmt.Fprintf(os.Stderr, "No plugin jars in %s, showing lock file dependencies only\n", dir)
+	}
+
+	for name, d := range descriptors {
+		deps := [][]string{d.Depend, d.SoftDepend}
+		for i, list := range deps {
+			for _, dep := range list {
+				edge := depEdge{to: strings.ToLower(dep), soft: i == 1, via: d.File}
+				if target, ok := byPluginName[strings.ToLower(dep)]; ok {
+					edge.to = target
+				} else {
+					edge.missing = true
+				}
+				g.add(name, edge)
+			}
+		}
+	}
+
+	for name := range g.edges {
+		sort.Slice(g.edges[name], func(i, j int) bool { return g.edges[name][i].to < g.edges[name][j].to })
+	}
+	return g
+}
+
+// add records an edge, keeping the strongest one if the dependency is
+// declared more than once.
+func (g *depGraph) add(from string, edge depEdge) {
+	for i, e := range g.edges[from] {
+		if e.to == edge.to {
+			if e.soft && !edge.soft {
+				g.edges[from][i] = edge
+			}
+			return
+		}
+	}
+	g.edges[from] = append(g.edges[from], edge)
+}
+
+// roots returns the entries listed in the manifest, sorted.
+func (g *depGraph) roots() []string {
+	var roots []string
+	for name, p := range g.lf.Plugins {
+		if !p.Transitive {
+			roots = append(roots, name)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// sources returns the names of the entries with dependencies, sorted.
+func (g *depGraph) sources() []string {
+	names := make([]string, 0, len(g.edges))
+	for name := range g.edges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// label describes a node for output.
+func (g *depGraph) label(name string) string {
+	if p, ok := g.lf.Plugins[name]; ok {
+		return name + " " + p.Version
+	}
+	return name
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PrimCraft/scaf/internal/jar"
+	"github.com/PrimCraft/scaf/internal/manifest"
+	"github.com/PrimCraft/scaf/internal/resolver"
+)
+
+var (
+	initDir      string
+	initPlatform string
+	initForce    bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a manifest from an existing plugins folder",
+	Long: `Scan a plugins folder and create a manifest and lock file for it.
+
+Each jar is identified by its hash on Modrinth, or by its declared plugin
+name and hash on Hangar. Identified plugins are pinned to their exact
+version. Jars that cannot be identified are listed as comments at the end
+of the manifest so they can be added by hand.`,
+	RunE: runInit,
+}
+
+func init() {
+	initCmd.Flags().StringVarP(&initDir, "dir", "d", "./plugins", "Plugins directory to scan")
+	initCmd.Flags().StringVar(&initPlatform, "platform", "", "Plugin platform, paper or velocity (default: detected per jar)")
+	initCmd.Flags().StringVarP(&manifestFile, "manifest", "m", "plugins.yaml", "Path to manifest file")
+	initCmd.Flags().StringVarP(&lockFile, "lockfile", "l", "plugins.lock.yaml", "Path to lock file")
+	initCmd.Flags().BoolVarP(&initForce, "force", "f", false, "Overwrite an existing manifest")
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if _, err := os.Stat(manifestFile); err == nil && !initForce {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", manifestFile)
+	}
+
+	jars, err := filepath.Glob(filepath.Join(initDir, "*.jar"))
+	if err != nil {
+		return err
+	}
+	if len(jars) == 0 {
+		return fmt.Errorf("no jars found in %s", initDir)
+	}
+
+	registry := resolver.NewRegistry()
+	lockfile := manifest.NewLockfile()
+
+	var data []byte
+	var unidentified []string
+
+	for _, path := range jars {
+		file := filepath.Base(path)
+		fmt.Fprintf(os.Stderr, "Identifying %s...\n", file)
+
+		info, err := jar.Inspect(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  -> not a plugin jar: %v\n", err)
+			unidentified = append(unidentified, file+" (unreadable)")
+			continue
+		}
+
+		local := resolver.LocalFile{
+			Platform: jarPlatform(info),
+			SHA1:     info.SHA1,
+			SHA256:   info.SHA256,
+			SHA512:   info.SHA512,
+		}
+		name := strings.ToLower(strings.TrimSuffix(file, ".jar"))
+		if d := info.Primary(); d != nil {
+			local.Name = d.Name
+			local.Version = d.Version
+			name = strings.ToLower(d.Name)
+		}
+
+		result, err := registry.Identify(ctx, local)
+		if err != nil {
+			return fmt.Errorf("identifying %s: %w", file, err)
+		}
+		if result == nil {
+			fmt.Fprintln(os.Stderr, "  -> not found")
+			unidentified = append(unidentified, fmt.Sprintf("%s (%s %s)", file, local.Name, local.Version))
+			continue
+		}
+		if _, exists := lockfile.Plugins[name]; exists {
+			name = uniqueName(lockfile, strings.ToLower(strings.TrimSuffix(file, ".jar")))
+			fmt.Fprintf(os.Stderr, "  (another jar declares the same name, adding it as %s)\n", name)
+		}
+
+		data, err = manifest.AddPlugin(data, name, &manifest.PluginConfig{
+			Source:   result.Source,
+			Project:  result.Project,
+			Version:  result.Version,
+			Platform: result.Platform,
+			Loader:   result.Loader,
+		})
+		if err != nil {
+			return err
+		}
+		entry := lockEntry(result)
+		entry.Constraint = result.Version
+		if entry.File, err = jarFile(manifest.Settings{}, "", name, entry); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		lockfile.Plugins[name] = entry
+		fmt.Fprintf(os.Stderr, "  -> %s:%s %s\n", result.Source, result.Project, result.Version)
+	}
+
+	content := fmt.Sprintf("# Generated by scaf init from %s\n\n", initDir)
+	content += string(data)
+	if len(unidentified) > 0 {
+		content += "\n# Could not identify the following jars, add them manually:\n"
+		for _, u := range unidentified {
+			content += "#   " + u + "\n"
+		}
+	}
+
+	if err := os.WriteFile(manifestFile, []byte(content), 0644); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	m, err := manifest.Load(manifestFile)
+	if err != nil {
+		return err
+	}
+	if lockfile.ManifestDigest, err = m.Digest(); err != nil {
+		return err
+	}
+	if err := lockfile.Write(lockFile); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "\nWrote %s and %s (%d identified, %d unidentified)\n",
+		manifestFile, lockFile, len(lockfile.Plugins), len(unidentified))
+	for _, u := range unidentified {
+		fmt.Fprintf(os.Stderr, "  unidentified: %s\n", u)
+	}
+	return nil
+}
+
+// uniqueName returns name, or name with a numeric suffix if lf already has
+// an entry of that name.
+func uniqueName(lf *manifest.Lockfile, name string) string {
+	unique := name
+	for i := 2; lf.Plugins[unique] != nil; i++ {
+		unique = fmt.Sprintf("%s-%d", name, i)
+	}
+	return unique
+}
+
+// jarPlatform picks the platform used to look up a jar upstream.
+func jarPlatform(info *jar.Info) string {
+	if initPlatform != "" {
+		return strings.ToLower(initPlatform)
+	}
+	if info.Has(jar.PlatformVelocity) && !info.Has(jar.PlatformBukkit) && !info.Has(jar.PlatformPaper) {
+		return "velocity"
+	}
+	return "paper"
+}
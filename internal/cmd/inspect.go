@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PrimCraft/scaf/internal/jar"
+	"github.com/PrimCraft/scaf/internal/manifest"
+	"github.com/PrimCraft/scaf/internal/resolver"
+)
+
+// targetPlatforms returns the platform a plugin runs on and the descriptor
+// platforms that load there, or nil if the target is unknown or its mods
+// are not inspected. The target
+// comes from the Hangar platform or Modrinth loader, and otherwise from the
+// server components in the lock file if they all run the same platform.
+func targetPlatforms(lf *manifest.Lockfile, p *manifest.ResolvedPlugin) (string, []jar.Platform) {
+	target := strings.ToLower(p.Platform)
+	switch strings.ToLower(p.Loader) {
+	case "velocity":
+		target = "velocity"
+	case "paper", "purpur", "folia", "spigot", "bukkit":
+		target = "paper"
+	case "bungeecord", "waterfall":
+		target = "bungee"
+	case "fabric", "neoforge", "forge":
+		target = strings.ToLower(p.Loader)
+	}
+	if target == "" {
+		platforms := make(map[string]bool)
+		for _, c := range lf.Components {
+			if platform := componentPlatform(c.Project); platform != "" {
+				platforms[platform] = true
+			}
+		}
+		if len(platforms) == 1 {
+			for platform := range platforms {
+				target = platform
+			}
+		}
+	}
+
+	switch target {
+	case "velocity":
+		return target, []jar.Platform{jar.PlatformVelocity}
+	case "paper":
+		return target, []jar.Platform{jar.PlatformPaper, jar.PlatformBukkit}
+	case "bungee", "waterfall":
+		return "bungee", []jar.Platform{jar.PlatformBungee}
+	case "fabric":
+		return target, []jar.Platform{jar.PlatformFabric}
+	case "neoforge", "forge":
+		return target, nil
+	}
+	return "", nil
+}
+
+// inspectJar checks that the jar downloaded for a plugin is a plugin for
+// its target platform and declares the locked version. It returns what the
+// matching descriptor declares, or nil for NeoForge and Forge mods, whose
+// TOML descriptors are not inspected.
+func inspectJar(lf *manifest.Lockfile, p *manifest.ResolvedPlugin, path string) (*manifest.Declared, error) {
+	target, platforms := targetPlatforms(lf, p)
+	if target == "neoforge" || target == "forge" {
+		return nil, nil
+	}
+
+	info, err := jar.Inspect(path)
+	if err != nil {
+		return nil, fmt.Errorf("inspecting jar: %w", err)
+	}
+	if len(info.Descriptors) == 0 {
+		return nil, fmt.Errorf("jar has no plugin descriptor")
+	}
+
+	d := info.Primary()
+	if platforms != nil {
+		if d = info.Find(platforms...); d == nil {
+			var found []string
+			for _, d := range info.Descriptors {
+				found = append(found, d.File)
+			}
+			return nil, fmt.Errorf("jar is not a %s plugin, it only has %s", target, strings.Join(found, ", "))
+		}
+	}
+
+	if d.Version != "" && p.Version != "unknown" && p.Version != "latest" && !versionsAgree(d.Version, p.Version) {
+		return nil, fmt.Errorf("jar declares version %s in %s, but %s is locked", d.Version, d.File, p.Version)
+	}
+
+	return &manifest.Declared{
+		Platform:   string(d.Platform),
+		Name:       d.Name,
+		Version:    d.Version,
+		Main:       d.Main,
+		APIVersion: d.APIVersion,
+		Depend:     d.Depend,
+		LoadBefore: d.LoadBefore,
+		Provides:   d.Provides,
+	}, nil
+}
+
+// checkCompatibility returns the problems that would stop Paper plugins
+// from loading on the locked Paper server: an api-version newer than the
+// server, or a required dependency that no locked jar declares. Plugins
+// without a recorded declaration are skipped.
+func checkCompatibility(lf *manifest.Lockfile) []string {
+	server := paperServer(lf)
+	if server == nil {
+		return nil
+	}
+
+	present := make(map[string]bool)
+	for name, p := range lf.Plugins {
+		present[strings.ToLower(name)] = true
+		if p.Declared != nil {
+			present[strings.ToLower(p.Declared.Name)] = true
+			for _, provided := range p.Declared.Provides {
+				present[strings.ToLower(provided)] = true
+			}
+		}
+	}
+
+	var problems []string
+	for _, name := range sortedPlugins(lf) {
+		d := lf.Plugins[name].Declared
+		if d == nil || (d.Platform != string(jar.PlatformPaper) && d.Platform != string(jar.PlatformBukkit)) {
+			continue
+		}
+		if d.APIVersion != "" {
+			if cmp, ok := resolver.CompareVersions(d.APIVersion, server.Version); ok && cmp > 0 {
+				problems = append(problems, fmt.Sprintf("%s: api-version %s is newer than %s %s", name, d.APIVersion, server.Project, server.Version))
+			}
+		}
+		for _, dep := range d.Depend {
+			if !present[strings.ToLower(dep)] {
+				problems = append(problems, fmt.Sprintf("%s: requires %s, which is not in the lock file", name, dep))
+			}
+		}
+	}
+	return problems
+}
+
+// sortedPlugins returns the names of the plugins in lf, sorted.
+func sortedPlugins(lf *manifest.Lockfile) []string {
+	names := make([]string, 0, len(lf.Plugins))
+	for name := range lf.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// versionsAgree reports whether a declared version matches a locked one.
+// Sources often decorate version numbers, so "v5.4.1" and "5.4.1-velocity"
+// both agree with "5.4.1".
+func versionsAgree(declared, locked string) bool {
+	declared = strings.TrimPrefix(strings.ToLower(declared), "v")
+	locked = strings.TrimPrefix(strings.ToLower(locked), "v")
+	if declared == locked {
+		return true
+	}
+	if cmp, ok := resolver.CompareVersions(declared, locked); ok && cmp == 0 {
+		return true
+	}
+	return containsVersion(locked, declared) || containsVersion(declared, locked)
+}
+
+// containsVersion reports whether s contains v as a whole version, not
+// followed or preceded by more version digits.
+func containsVersion(s, v string) bool {
+	i := strings.Index(s, v)
+	if i < 0 {
+		return false
+	}
+	isPart := func(c byte) bool { return c == '.' || (c >= '0' && c <= '9') }
+	if i > 0 && isPart(s[i-1]) {
+		return false
+	}
+	end := i + len(v)
+	return end == len(s) || !isPart(s[end])
+}
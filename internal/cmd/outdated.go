@@ -0,0 +1,266 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+	"github.com/PrimCraft/scaf/internal/resolver"
+)
+
+var (
+	outdatedFormat string
+	outdatedAll    bool
+)
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated",
+	Short: "Show locked plugins with newer versions available",
+	Long: `For each entry in the lock file, show the current version, the newest
+version allowed by the manifest constraint (wanted) and the newest version
+available upstream (latest), classifying the gap as patch, minor or major.
+
+Output formats: table, json, markdown`,
+	RunE: runOutdated,
+}
+
+func init() {
+	outdatedCmd.Flags().StringVarP(&manifestFile, "manifest", "m", "plugins.yaml", "Path to manifest file")
+	outdatedCmd.Flags().StringVarP(&lockFile, "lockfile", "l", "plugins.lock.yaml", "Path to lock file")
+	outdatedCmd.Flags().StringVarP(&outdatedFormat, "format", "f", "table", "Output format (table, json, markdown)")
+	outdatedCmd.Flags().BoolVarP(&outdatedAll, "all", "a", false, "Include entries that are up to date")
+}
+
+// outdatedEntry is a single row of the outdated report.
+type outdatedEntry struct {
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Current string `json:"current"`
+	Wanted  string `json:"wanted,omitempty"`
+	Latest  string `json:"latest,omitempty"`
+	Bump    string `json:"bump,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+func runOutdated(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	switch outdatedFormat {
+	case "table", "json", "markdown":
+	default:
+		return fmt.Errorf("unknown format %q", outdatedFormat)
+	}
+
+	m, err := manifest.Load(manifestFile)
+	if err != nil {
+		return err
+	}
+	lf, err := manifest.LoadLockfile(lockFile)
+	if err != nil {
+		return err
+	}
+
+	registry := resolver.NewRegistry()
+	var entries []outdatedEntry
+
+	check := func(name, current string, cfg resolver.PluginConfig) {
+		fmt.Fprintf(os.Stderr, "Checking %s...\n", name)
+		entry := outdatedEntry{Name: name, Source: cfg.Source, Current: current}
+
+		versions, err := registry.Versions(ctx, cfg.Source, cfg)
+		if errors.Is(err, resolver.ErrUnsupported) {
+			if outdatedAll {
+				entries = append(entries, entry)
+			}
+			return
+		}
+		if err == nil {
+			entry.Wanted, err = resolver.SelectBestVersion(versions, cfg.Version)
+		}
+		if err != nil {
+			entry.Error = err.Error()
+			entries = append(entries, entry)
+			return
+		}
+		entry.Latest, _ = resolver.SelectBestVersion(versions, "latest")
+		entry.Bump = resolver.Bump(current, entry.Latest)
+
+		if outdatedAll || entry.Wanted != current || entry.Latest != current {
+			entries = append(entries, entry)
+		}
+	}
+
+	// Modded servers are resolved in full to see loader and installer
+	// updates, which version lists do not show
+	checkModded := func(name string, locked *manifest.ResolvedComponent, cfg resolver.PluginConfig) {
+		fmt.Fprintf(os.Stderr, "Checking %s...\n", name)
+		entry := outdatedEntry{Name: name, Source: cfg.Source, Current: componentVersion(locked)}
+
+		wanted, err := resolveModded(ctx, registry, cfg)
+		var latest *manifest.ResolvedComponent
+		if err == nil {
+			latest, err = resolveModded(ctx, registry, resolver.PluginConfig{Source: cfg.Source, Project: cfg.Project, Version: "latest"})
+		}
+		if err != nil {
+			entry.Error = err.Error()
+			entries = append(entries, entry)
+			return
+		}
+		entry.Wanted, entry.Latest = componentVersion(wanted), componentVersion(latest)
+		entry.Bump = componentBump(locked, latest)
+
+		if outdatedAll || entry.Wanted != entry.Current || entry.Latest != entry.Current {
+			entries = append(entries, entry)
+		}
+	}
+
+	components := m.AllComponents()
+	for _, name := range slices.Sorted(maps.Keys(lf.Components)) {
+		locked := lf.Components[name]
+		cfg := resolver.PluginConfig{Source: locked.Source, Project: locked.Project, Version: locked.Constraint}
+		if c, ok := components[name]; ok {
+			cfg.Version = c.Version
+			cfg.LoaderVersion = c.Loader
+			cfg.InstallerVersion = c.Installer
+		}
+		if locked.Loader != "" {
+			checkModded(name, locked, cfg)
+			continue
+		}
+		check(name, locked.Version, cfg)
+	}
+
+	names := make([]string, 0, len(lf.Plugins))
+	for name := range lf.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		locked := lf.Plugins[name]
+
+		// Prefer the manifest entry, it carries the constraint and filters
+		cfg := resolver.PluginConfig{
+			Source:   locked.Source,
+			Project:  locked.Project,
+			Platform: locked.Platform,
+			Loader:   locked.Loader,
+		}
+		if plugin, ok := m.Plugins[name]; ok {
+			cfg = resolverConfig(plugin)
+			if server := paperServer(lf); server != nil {
+				cfg = withGameVersions(m.Settings, server.Version, cfg)
+			}
+		}
+		check(name, locked.Version, cfg)
+	}
+
+	var paper string
+	if server := paperServer(lf); server != nil {
+		paper = server.Version
+	}
+	for _, kind := range packKinds {
+		packs := kind.locked(lf)
+		for _, name := range slices.Sorted(maps.Keys(packs)) {
+			locked := packs[name]
+			cfg := resolver.PluginConfig{
+				Source:       locked.Source,
+				Project:      locked.Project,
+				Version:      locked.Constraint,
+				Loader:       kind.loader,
+				GameVersions: locked.GameVersions,
+			}
+			if p, ok := kind.config(m)[name]; ok {
+				cfg = packConfig(m.Settings, paper, kind, name, p)
+			}
+			check(name, locked.Version, cfg)
+		}
+	}
+
+	switch outdatedFormat {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if entries == nil {
+			entries = []outdatedEntry{}
+		}
+		return enc.Encode(entries)
+	case "markdown":
+		writeOutdatedMarkdown(os.Stdout, entries)
+	default:
+		writeOutdatedTable(os.Stdout, entries)
+	}
+	return nil
+}
+
+func writeOutdatedTable(w io.Writer, entries []outdatedEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "Everything is up to date")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSOURCE\tCURRENT\tWANTED\tLATEST\tBUMP")
+	for _, e := range entries {
+		if e.Error != "" {
+			fmt.Fprintf(tw, "%s\t%s\t%s\terror: %s\t\t\n", e.Name, e.Source, e.Current, e.Error)
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Name, e.Source, e.Current, orDash(e.Wanted), orDash(e.Latest), orDash(e.Bump))
+	}
+	_ = tw.Flush()
+}
+
+func writeOutdatedMarkdown(w io.Writer, entries []outdatedEntry) {
+	if len(entries) == 0 {
+		fmt.Fprintln(w, "Everything is up to date")
+		return
+	}
+
+	fmt.Fprintln(w, "| Name | Source | Current | Wanted | Latest | Bump |")
+	fmt.Fprintln(w, "|------|--------|---------|--------|--------|------|")
+	for _, e := range entries {
+		wanted := orDash(e.Wanted)
+		if e.Error != "" {
+			wanted = "error: " + strings.ReplaceAll(e.Error, "|", `\|`)
+		}
+		fmt.Fprintf(w, "| **%s** | %s | %s | %s | %s | %s |\n",
+			e.Name, e.Source, e.Current, wanted, orDash(e.Latest), orDash(e.Bump))
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// resolveModded resolves a modded server component, returning the parts
+// componentVersion shows.
+func resolveModded(ctx context.Context, registry *resolver.Registry, cfg resolver.PluginConfig) (*manifest.ResolvedComponent, error) {
+	result, err := registry.Resolve(ctx, cfg.Source, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &manifest.ResolvedComponent{
+		Version:   result.Version,
+		Build:     result.Build,
+		Loader:    result.LoaderVersion,
+		Installer: result.InstallerVersion,
+	}, nil
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+	"github.com/PrimCraft/scaf/internal/resolver"
+)
+
+// packKind is a manifest section of packs resolved from Modrinth.
+type packKind struct {
+	loader string // Modrinth loader the packs are listed under
+	dest   func(settings manifest.Settings) string
+	config func(m *manifest.Manifest) map[string]*manifest.PackConfig
+	locked func(lf *manifest.Lockfile) map[string]*manifest.ResolvedPack
+}
+
+var packKinds = []packKind{
+	{
+		loader: "datapack",
+		dest:   manifest.Settings.DatapackDest,
+		config: func(m *manifest.Manifest) map[string]*manifest.PackConfig { return m.Datapacks },
+		locked: func(lf *manifest.Lockfile) map[string]*manifest.ResolvedPack { return lf.Datapacks },
+	},
+	{
+		loader: "minecraft",
+		dest:   func(manifest.Settings) string { return manifest.ResourcepackDest },
+		config: func(m *manifest.Manifest) map[string]*manifest.PackConfig { return m.Resourcepacks },
+		locked: func(lf *manifest.Lockfile) map[string]*manifest.ResolvedPack { return lf.Resourcepacks },
+	},
+}
+
+// packConfig converts a pack entry into resolver input. With
+// infer_game_versions, packs without game_versions follow the Paper
+// version like Paper plugins.
+func packConfig(settings manifest.Settings, paper string, kind packKind, name string, p *manifest.PackConfig) resolver.PluginConfig {
+	project := p.Project
+	if project == "" {
+		project = name
+	}
+	cfg := resolver.PluginConfig{
+		Source:       "modrinth",
+		Project:      project,
+		Version:      p.Version,
+		Loader:       kind.loader,
+		GameVersions: p.GameVersions,
+	}
+	if settings.InferGameVersions && paper != "" && len(cfg.GameVersions) == 0 {
+		cfg.GameVersions = []string{paper}
+	}
+	return cfg
+}
+
+// resolvePack resolves a datapack or resource pack entry.
+func (r *resolution) resolvePack(ctx context.Context, kind packKind, name string, p *manifest.PackConfig) (*manifest.ResolvedPack, error) {
+	cfg := packConfig(r.settings, r.paper, kind, name, p)
+	fmt.Fprintf(os.Stderr, "Resolving %s from %s (constraint: %s)...\n", name, cfg.Source, cfg.Version)
+
+	if r.previous != nil {
+		if locked, ok := kind.locked(r.previous)[name]; ok && locked.Project == cfg.Project {
+			cfg.Current = locked.Version
+		}
+	}
+	r.apply(&cfg, p.Policy)
+
+	result, err := r.registry.Resolve(ctx, cfg.Source, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", name, err)
+	}
+	fmt.Fprintf(os.Stderr, "  -> %s\n", result.Version)
+	r.noteHeld(name, result, cfg.MaxBump)
+
+	entry := &manifest.ResolvedPack{
+		Source:       result.Source,
+		Project:      result.Project,
+		Constraint:   p.Version,
+		Version:      result.Version,
+		PublishedAt:  result.PublishedAt,
+		GameVersions: cfg.GameVersions,
+		URL:          result.URL,
+		SHA1:         result.SHA1,
+		SHA512:       result.SHA512,
+	}
+	if entry.File, err = packFile(r.settings, kind, name, p, entry); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return entry, nil
+}
+
+// packFile returns where download puts a pack, relative to the server
+// directory. The file keeps the extension of the upstream file, .zip for
+// most packs.
+func packFile(settings manifest.Settings, kind packKind, name string, p *manifest.PackConfig, locked *manifest.ResolvedPack) (string, error) {
+	dest := p.Dest
+	if dest == "" {
+		dest = kind.dest(settings)
+	}
+	ext := ".zip"
+	if u, err := url.Parse(locked.URL); err == nil && path.Ext(u.Path) != "" {
+		ext = path.Ext(u.Path)
+	}
+	return manifest.JarFile(dest, "{name}"+ext, name, locked.Version, "")
+}
+
+// packStale reports whether a locked pack no longer matches its manifest
+// entry.
+func packStale(settings manifest.Settings, paper string, kind packKind, name string, p *manifest.PackConfig, locked *manifest.ResolvedPack) bool {
+	cfg := packConfig(settings, paper, kind, name, p)
+	if cfg.Project != locked.Project || cfg.Version != locked.Constraint || !slices.Equal(cfg.GameVersions, locked.GameVersions) {
+		return true
+	}
+	if file, err := packFile(settings, kind, name, p, locked); err != nil || file != locked.File {
+		return true
+	}
+	return !resolver.Satisfies(locked.Version, cfg.Version)
+}
+
+// downloadPack downloads a locked pack into the server directory dir and
+// checks its hashes.
+func downloadPack(ctx context.Context, client *http.Client, dir, name string, p *manifest.ResolvedPack) error {
+	fmt.Fprintf(os.Stderr, "Downloading %s %s...\n", name, p.Version)
+	dest, err := lockedPath(dir, p.File)
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", name, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("creating directory for %s: %w", name, err)
+	}
+	if err := downloadHTTP(ctx, client, p.URL, dest); err != nil {
+		return fmt.Errorf("downloading %s: %w", name, err)
+	}
+	if err := checkHashes(dest, "", p.SHA1, p.SHA512); err != nil {
+		return fmt.Errorf("downloading %s: %w", name, err)
+	}
+	fmt.Fprintf(os.Stderr, "  -> %s\n", dest)
+	return nil
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+)
+
+var propertiesWrite string
+
+var propertiesCmd = &cobra.Command{
+	Use:   "properties [resourcepack]",
+	Short: "Print the server.properties settings of a locked resource pack",
+	Long: `Print the resource-pack and resource-pack-sha1 settings of
+server.properties for a resource pack in the lock file, so players are sent
+the locked version and their client checks its SHA-1. The name can be left
+out if the lock file has a single resource pack.
+
+With --write, the settings are written to the given server.properties
+instead, keeping its other lines. The file is created if it does not
+exist.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runProperties,
+}
+
+func init() {
+	propertiesCmd.Flags().StringVarP(&lockFile, "lockfile", "l", "plugins.lock.yaml", "Path to lock file")
+	propertiesCmd.Flags().StringVarP(&propertiesWrite, "write", "w", "", "Update this server.properties file instead of printing")
+}
+
+func runProperties(cmd *cobra.Command, args []string) error {
+	lf, err := manifest.LoadLockfile(lockFile)
+	if err != nil {
+		return err
+	}
+
+	var name string
+	switch {
+	case len(args) == 1:
+		name = args[0]
+	case len(lf.Resourcepacks) == 1:
+		name = slices.Collect(maps.Keys(lf.Resourcepacks))[0]
+	case len(lf.Resourcepacks) == 0:
+		return fmt.Errorf("%s has no resource packs", lockFile)
+	default:
+		return fmt.Errorf("%s has several resource packs, name one of %s", lockFile,
+			strings.Join(slices.Sorted(maps.Keys(lf.Resourcepacks)), ", "))
+	}
+	pack, ok := lf.Resourcepacks[name]
+	if !ok {
+		return fmt.Errorf("%s is not a resource pack in %s", name, lockFile)
+	}
+	if pack.SHA1 == "" {
+		return fmt.Errorf("%s has no SHA-1 in %s, run 'scaf update %s'", name, lockFile, name)
+	}
+
+	settings := [][2]string{
+		{"resource-pack", pack.URL},
+		{"resource-pack-sha1", pack.SHA1},
+	}
+	if propertiesWrite == "" {
+		for _, s := range settings {
+			fmt.Printf("%s=%s\n", s[0], escapeProperty(s[1]))
+		}
+		return nil
+	}
+
+	data, err := os.ReadFile(propertiesWrite)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading %s: %w", propertiesWrite, err)
+	}
+	if err := os.WriteFile(propertiesWrite, setProperties(data, settings), 0644); err != nil {
+		return fmt.Errorf("writing %s: %w", propertiesWrite, err)
+	}
+	fmt.Fprintf(os.Stderr, "Set %s %s in %s\n", name, pack.Version, propertiesWrite)
+	return nil
+}
+
+// setProperties sets keys in the contents of a .properties file, replacing
+// existing lines for them and appending the others.
+func setProperties(data []byte, settings [][2]string) []byte {
+	text := strings.TrimSuffix(string(data), "\n")
+	var lines []string
+	if text != "" {
+		lines = strings.Split(text, "\n")
+	}
+
+	for _, s := range settings {
+		line := s[0] + "=" + escapeProperty(s[1])
+		i := slices.IndexFunc(lines, func(l string) bool {
+			key, _, _ := strings.Cut(strings.TrimSpace(l), "=")
+			return strings.TrimSpace(key) == s[0]
+		})
+		if i < 0 {
+			lines = append(lines, line)
+		} else {
+			lines[i] = line
+		}
+	}
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// escapeProperty escapes a value the way the server writes
+// server.properties.
+func escapeProperty(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ":", `\:`, "=", `\=`).Replace(value)
+}
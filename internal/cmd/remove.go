@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+)
+
+var removeCmd = &cobra.Command{
+	Use:   "remove <name>...",
+	Short: "Remove plugins from the manifest",
+	Long:  `Remove plugin entries from the manifest and the lock file, keeping existing comments and ordering.`,
+	Args:  cobra.MinimumNArgs(1),
+	RunE:  runRemove,
+}
+
+func init() {
+	removeCmd.Flags().StringVarP(&manifestFile, "manifest", "m", "plugins.yaml", "Path to manifest file")
+	removeCmd.Flags().StringVarP(&lockFile, "lockfile", "l", "plugins.lock.yaml", "Path to lock file")
+}
+
+func runRemove(cmd *cobra.Command, args []string) error {
+	data, err := os.ReadFile(manifestFile)
+	if err != nil {
+		return fmt.Errorf("reading manifest: %w", err)
+	}
+
+	for _, name := range args {
+		data, err = manifest.RemovePlugin(data, name)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := os.WriteFile(manifestFile, data, 0644); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	for _, name := range args {
+		fmt.Fprintf(os.Stderr, "Removed %s from %s\n", name, manifestFile)
+	}
+
+	return updateLockfile(func(lf *manifest.Lockfile) error {
+		for _, name := range args {
+			delete(lf.Plugins, name)
+			dropRequirer(lf, name)
+		}
+		pruneTransitive(lf)
+		return nil
+	})
+}
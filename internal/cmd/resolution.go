@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+	"github.com/PrimCraft/scaf/internal/resolver"
+)
+
+// allowMajor disables the max_bump policy for resolve and update.
+var allowMajor bool
+
+// resolution holds the state shared by all entries resolved in one run.
+type resolution struct {
+	registry *resolver.Registry
+	settings manifest.Settings
+
+	// previous is the existing lock file, the baseline for max_bump. It
+	// may be nil.
+	previous *manifest.Lockfile
+
+	// paper is the resolved Paper version, for infer_game_versions.
+	paper string
+
+	// held lists the entries max_bump kept on an older version.
+	held []string
+
+	// pending are the dependencies of resolved entries that
+	// resolveDependencies has not handled yet.
+	pending []dependency
+}
+
+func newResolution(m *manifest.Manifest, previous *manifest.Lockfile) *resolution {
+	r := &resolution{
+		registry: resolver.NewRegistry(),
+		settings: m.Settings,
+		previous: previous,
+	}
+	if previous == nil {
+		return r
+	}
+	// Until the server is resolved again, plugins follow the locked one
+	for _, c := range m.AllComponents() {
+		if componentPlatform(c.Project) == "paper" {
+			r.followServer(previous)
+			break
+		}
+	}
+	return r
+}
+
+// followServer makes entries resolved from now on follow the Paper server
+// of lf, picked like paperServer does everywhere else.
+func (r *resolution) followServer(lf *manifest.Lockfile) {
+	r.paper = ""
+	if server := paperServer(lf); server != nil {
+		r.paper = server.Version
+	}
+}
+
+// resolveComponent resolves a server/proxy component.
+func (r *resolution) resolveComponent(ctx context.Context, name string, c *manifest.ComponentConfig) (*manifest.ResolvedComponent, error) {
+	fmt.Fprintf(os.Stderr, "Resolving %s from %s (constraint: %s)...\n", componentLabel(name, c.Project), c.Source, c.Version)
+
+	cfg := resolver.PluginConfig{
+		Source:           c.Source,
+		Project:          c.Project,
+		Version:          c.Version,
+		LoaderVersion:    c.Loader,
+		InstallerVersion: c.Installer,
+	}
+	if r.previous != nil {
+		if locked, ok := r.previous.Components[name]; ok && locked.Source == c.Source && locked.Project == c.Project {
+			cfg.Current = locked.Version
+		}
+	}
+	r.apply(&cfg, c.Policy)
+
+	result, err := r.registry.Resolve(ctx, cfg.Source, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", name, err)
+	}
+	switch {
+	case result.InstallerVersion != "":
+		fmt.Fprintf(os.Stderr, "  -> %s (loader %s, installer %s)\n", result.Version, result.LoaderVersion, result.InstallerVersion)
+	case result.LoaderVersion != "":
+		fmt.Fprintf(os.Stderr, "  -> %s (loader %s)\n", result.Version, result.LoaderVersion)
+	default:
+		fmt.Fprintf(os.Stderr, "  -> %s\n", formatVersion(result.Version, result.Build))
+	}
+	r.noteHeld(name, result, cfg.MaxBump)
+
+	return &manifest.ResolvedComponent{
+		Source:      c.Source,
+		Project:     c.Project,
+		Constraint:  c.Version,
+		Version:     result.Version,
+		Build:       result.Build,
+		PublishedAt: result.PublishedAt,
+		URL:         result.URL,
+		MD5:         result.MD5,
+		SHA1:        result.SHA1,
+		Loader:      result.LoaderVersion,
+		Installer:   result.InstallerVersion,
+		File:        c.Path(),
+	}, nil
+}
+
+// resolvePlugin resolves a single manifest plugin entry.
+func (r *resolution) resolvePlugin(ctx context.Context, name string, plugin *manifest.PluginConfig) (*manifest.ResolvedPlugin, error) {
+	cfg := withGameVersions(r.settings, r.paper, resolverConfig(plugin))
+	entry, err := r.resolve(ctx, name, cfg, plugin.Policy)
+	if err != nil {
+		return nil, err
+	}
+	entry.Constraint = plugin.Version
+	entry.GameVersions = cfg.GameVersions
+	if plugin.Dest != "" {
+		if entry.File, err = jarFile(r.settings, plugin.Dest, name, entry); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return entry, nil
+}
+
+// resolve resolves cfg into a lock file entry named name and queues its
+// dependencies.
+func (r *resolution) resolve(ctx context.Context, name string, cfg resolver.PluginConfig, policy manifest.Policy) (*manifest.ResolvedPlugin, error) {
+	fmt.Fprintf(os.Stderr, "Resolving %s from %s (constraint: %s)...\n", name, cfg.Source, cfg.Version)
+
+	// The baseline only applies while the entry points at the same project
+	if r.previous != nil {
+		if locked, ok := r.previous.Plugins[name]; ok && locked.Source == cfg.Source && locked.Project == cfg.Project {
+			cfg.Current = locked.Version
+		}
+	}
+	r.apply(&cfg, policy)
+
+	result, err := r.registry.Resolve(ctx, cfg.Source, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", name, err)
+	}
+	fmt.Fprintf(os.Stderr, "  -> %s\n", result.Version)
+	r.noteHeld(name, result, cfg.MaxBump)
+
+	entry := lockEntry(result)
+	if entry.File, err = jarFile(r.settings, "", name, entry); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	for _, dep := range result.Dependencies {
+		r.pending = append(r.pending, dependency{Dependency: dep, parent: name, from: entry, cfg: cfg})
+	}
+	return entry, nil
+}
+
+// apply sets the effective max_bump and min_age policy on cfg.
+func (r *resolution) apply(cfg *resolver.PluginConfig, entry manifest.Policy) {
+	policy := entry.Merge(r.settings.Policy)
+	if !allowMajor {
+		cfg.MaxBump = policy.MaxBump
+	}
+	// The manifest was validated on load
+	cfg.MinAge, _ = manifest.ParseAge(policy.MinAge)
+}
+
+func (r *resolution) noteHeld(name string, result *resolver.Result, maxBump string) {
+	if result.HeldBack == "" {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "  (held back from %s by max_bump %s)\n", result.HeldBack, maxBump)
+	r.held = append(r.held, fmt.Sprintf("%s: kept %s, %s is available (max_bump: %s)",
+		name, result.Version, result.HeldBack, maxBump))
+}
+
+// reportHeld prints the entries that max_bump kept back.
+func (r *resolution) reportHeld() {
+	if len(r.held) == 0 {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "\nHeld back by max_bump (use --allow-major to take them):")
+	for _, line := range r.held {
+		fmt.Fprintf(os.Stderr, "  - %s\n", line)
+	}
+}
@@ -3,11 +3,15 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"maps"
+	"net/url"
 	"os"
-	"regexp"
+	"path"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/goccy/go-yaml"
 	"github.com/spf13/cobra"
 
 	"github.com/PrimCraft/scaf/internal/manifest"
@@ -18,6 +22,9 @@ var (
 	manifestFile string
 	outputFile   string
 	checkMode    bool
+	frozenMode   bool
+
+	withTimestamps bool
 )
 
 var resolveCmd = &cobra.Command{
@@ -30,14 +37,85 @@ Version constraints (semver):
   "5.4.3"            Exact version
   ">=5.0.0,<6.0.0"   Range constraint
   "~5.4"             Patch-level changes allowed (>=5.4.0, <5.5.0)
-  "^5.4"             Minor-level changes allowed (>=5.4.0, <6.0.0)`,
+  "^5.4"             Minor-level changes allowed (>=5.4.0, <6.0.0)
+
+Required dependencies declared on Modrinth and Hangar are added to the
+lock file as transitive entries, recording the entries that require them.
+Resolution fails if an incompatible plugin is present or a required
+dependency cannot be resolved, such as one Hangar lists only by an external
+URL; add those to the manifest by hand. Missing optional dependencies are
+reported as warnings. Resolution also fails if two entries resolve to the
+same project, file or URL.
+
+With --check, the existing lock file is verified against the manifest
+instead of being rewritten: the command exits 1 if an entry was added,
+removed or changed in the manifest. Newer upstream versions are reported
+but do not fail the check. Add --frozen to skip the network entirely.
+
+Set max_bump (major, minor or patch) under settings, or on a single entry,
+to stop an upgrade from crossing that boundary relative to the version in
+the existing lock file. Held back upgrades are reported; pass --allow-major
+to take them anyway. Set min_age (e.g. "3d" or "12h") the same way to skip
+versions and builds published more recently than that.
+
+Server and proxy jars are listed under components, each with a version
+and optionally a source (papermc, or purpur, fabric, neoforge and forge for
+those projects), project (the component name by default), file
+(<project>.jar, or <project>-installer.jar for NeoForge and Forge) and the
+policies below:
+
+  components:
+    proxy:
+      project: velocity
+      version: "~3.4"
+    lobby:
+      project: folia
+      version: "1.21.4"
+    events:
+      project: fabric
+      version: "1.21.1"    # Minecraft version
+      loader: "~0.16"      # Fabric loader, newest stable by default
+      installer: latest    # Fabric installer
+    modded:
+      project: neoforge
+      version: "1.21.1"    # Minecraft version
+      loader: "~21.1"      # NeoForge version
+
+The velocity and paper keys are shorthands for components of those names.
+Set flavor under paper to run folia or purpur instead of Paper. Fabric Meta
+and the NeoForge and Forge Maven repositories do not publish release
+dates, so min_age does not apply to them.
+
+Datapacks and resource packs from Modrinth are listed under datapacks and
+resourcepacks, each with a version and optionally a project (the entry
+name by default), game_versions, dest and the policies above:
+
+  settings:
+    world: survival        # world folder, "world" by default
+  datapacks:
+    terralith:
+      version: "~2.5"      # downloaded to <server-dir>/survival/datapacks
+  resourcepacks:
+    fresh-animations:
+      version: latest      # downloaded to <server-dir>/resourcepacks
+
+Run 'scaf properties' to print the resource-pack settings of
+server.properties for a locked resource pack.
+
+With infer_game_versions: true under settings, Paper plugins from Modrinth
+and Hangar, and datapacks and resource packs, that set no game_versions are
+restricted to versions supporting the resolved Paper version, so bumping
+Paper fails unless every entry has a compatible version.`,
 	RunE: runResolve,
 }
 
 func init() {
 	resolveCmd.Flags().StringVarP(&manifestFile, "manifest", "m", "plugins.yaml", "Path to manifest file")
 	resolveCmd.Flags().StringVarP(&outputFile, "output", "o", "plugins.lock.yaml", "Path to output lock file")
-	resolveCmd.Flags().BoolVar(&checkMode, "check", false, "Check if lock file is up to date (exit 1 if not)")
+	resolveCmd.Flags().BoolVar(&checkMode, "check", false, "Check if lock file satisfies the manifest (exit 1 if not)")
+	resolveCmd.Flags().BoolVar(&withTimestamps, "timestamps", false, "Record resolution times in the lock file")
+	resolveCmd.Flags().BoolVar(&frozenMode, "frozen", false, "With --check, only verify offline and skip the upstream report")
+	resolveCmd.Flags().BoolVar(&allowMajor, "allow-major", false, "Ignore max_bump and take any version the constraints allow")
 }
 
 func runResolve(cmd *cobra.Command, args []string) error {
@@ -45,133 +123,272 @@ func runResolve(cmd *cobra.Command, args []string) error {
 	defer cancel()
 
 	// Load manifest
-	data, err := os.ReadFile(manifestFile)
+	m, err := manifest.Load(manifestFile)
 	if err != nil {
-		return fmt.Errorf("reading manifest: %w", err)
+		return err
 	}
 
-	var m manifest.Manifest
-	if err := yaml.Unmarshal(data, &m); err != nil {
-		return fmt.Errorf("parsing manifest: %w", err)
+	digest, err := m.Digest()
+	if err != nil {
+		return err
 	}
 
-	// Create registry and lockfile
-	registry := resolver.NewRegistry()
-	lockfile := manifest.NewLockfile()
+	// Check mode: verify the existing lock file instead of writing one
+	if checkMode {
+		return checkLockfile(ctx, m, digest)
+	}
 
-	// Resolve Velocity if configured
-	if m.Velocity.Version != "" {
-		fmt.Fprintf(os.Stderr, "Resolving Velocity (constraint: %s)...\n", m.Velocity.Version)
-		result, err := registry.Resolve(ctx, "papermc", resolver.PluginConfig{
-			Project: "velocity",
-			Version: m.Velocity.Version,
-		})
-		if err != nil {
-			return fmt.Errorf("resolving velocity: %w", err)
-		}
-		lockfile.Velocity = &manifest.ResolvedComponent{
-			Version: result.Version,
-			Build:   result.Build,
-			URL:     result.URL,
+	// The existing lock file, if any, is the baseline for max_bump
+	var previous *manifest.Lockfile
+	if _, err := os.Stat(outputFile); err == nil {
+		if previous, err = manifest.LoadLockfile(outputFile); err != nil {
+			return err
 		}
-		fmt.Fprintf(os.Stderr, "  -> %s (build %d)\n", result.Version, result.Build)
 	}
 
-	// Resolve Paper if configured
-	if m.Paper.Version != "" {
-		fmt.Fprintf(os.Stderr, "Resolving Paper (constraint: %s)...\n", m.Paper.Version)
-		result, err := registry.Resolve(ctx, "papermc", resolver.PluginConfig{
-			Project: "paper",
-			Version: m.Paper.Version,
-		})
+	res := newResolution(m, previous)
+	lockfile := manifest.NewLockfile()
+
+	// Resolve server components
+	components := m.AllComponents()
+	for _, name := range slices.Sorted(maps.Keys(components)) {
+		lockfile.Components[name], err = res.resolveComponent(ctx, name, components[name])
 		if err != nil {
-			return fmt.Errorf("resolving paper: %w", err)
-		}
-		lockfile.Paper = &manifest.ResolvedComponent{
-			Version: result.Version,
-			Build:   result.Build,
-			URL:     result.URL,
+			return err
 		}
-		fmt.Fprintf(os.Stderr, "  -> %s (build %d)\n", result.Version, result.Build)
 	}
+	res.followServer(lockfile)
 
 	// Resolve plugins
 	for name, plugin := range m.Plugins {
-		source := plugin.Source
-		if source == "" {
-			source = "hangar"
+		resolved, err := res.resolvePlugin(ctx, name, plugin)
+		if err != nil {
+			return err
 		}
+		lockfile.Plugins[name] = resolved
+	}
 
-		fmt.Fprintf(os.Stderr, "Resolving %s from %s (constraint: %s)...\n", name, source, plugin.Version)
-
-		cfg := resolver.PluginConfig{
-			Source:       source,
-			Project:      plugin.Project,
-			Version:      plugin.Version,
-			Platform:     plugin.Platform,
-			Loader:       plugin.Loader,
-			GameVersions: plugin.GameVersions,
-			Bucket:       plugin.Bucket,
-			Key:          plugin.Key,
-			URL:          plugin.URL,
+	// Resolve datapacks and resource packs
+	for _, kind := range packKinds {
+		for name, p := range kind.config(m) {
+			resolved, err := res.resolvePack(ctx, kind, name, p)
+			if err != nil {
+				return err
+			}
+			kind.locked(lockfile)[name] = resolved
 		}
+	}
 
-		result, err := registry.Resolve(ctx, source, cfg)
-		if err != nil {
-			return fmt.Errorf("resolving %s: %w", name, err)
-		}
+	// Add the plugins' dependencies
+	if err := res.resolveDependencies(ctx, lockfile); err != nil {
+		return err
+	}
+	if problems := checkConflicts(lockfile); len(problems) > 0 {
+		return conflictError(problems)
+	}
 
-		lockfile.Plugins[name] = &manifest.ResolvedPlugin{
-			Source:     result.Source,
-			Project:    result.Project,
-			Version:    result.Version,
-			Platform:   result.Platform,
-			Loader:     result.Loader,
-			URL:        result.URL,
-			S3URI:      result.S3URI,
-			SHA256:     result.SHA256,
-			SHA512:     result.SHA512,
-			ResolvedAt: result.ResolvedAt,
+	if withTimestamps {
+		now := time.Now().UTC()
+		lockfile.ResolvedAt = now
+		for _, p := range lockfile.Plugins {
+			p.ResolvedAt = now
 		}
-		fmt.Fprintf(os.Stderr, "  -> %s\n", result.Version)
 	}
 
-	// Generate output
-	output, err := yaml.Marshal(lockfile)
+	lockfile.ManifestDigest = digest
+	if err := lockfile.Write(outputFile); err != nil {
+		return err
+	}
+
+	res.reportHeld()
+	fmt.Fprintf(os.Stderr, "\nWrote %s\n", outputFile)
+	return nil
+}
+
+// checkLockfile verifies offline that the lock file still satisfies the
+// manifest, exiting 1 if not. Unless frozen, it then resolves the manifest
+// again and reports entries with newer upstream versions, without failing.
+func checkLockfile(ctx context.Context, m *manifest.Manifest, digest string) error {
+	lf, err := manifest.LoadLockfile(outputFile)
 	if err != nil {
-		return fmt.Errorf("marshaling lockfile: %w", err)
+		return err
 	}
 
-	content := "# Auto-generated lock file - do not edit manually\n"
-	content += "# Generated by scaf\n\n"
-	content += string(output)
+	if stale := staleEntries(m, lf); len(stale) > 0 {
+		fmt.Fprintln(os.Stderr, "Lock file is out of sync with the manifest:")
+		for _, name := range stale {
+			fmt.Fprintf(os.Stderr, "  - %s\n", name)
+		}
+		fmt.Fprintln(os.Stderr, "\nRun 'scaf update' to update them.")
+		os.Exit(1)
+	}
+	if lf.ManifestDigest != "" && lf.ManifestDigest != digest {
+		fmt.Fprintln(os.Stderr, "Manifest changed since the lock file was written. Run 'scaf update' to update it.")
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "Lock file satisfies the manifest.")
+	if lf.Migrated() {
+		fmt.Fprintf(os.Stderr, "Lock file uses an older format, run 'scaf update' to migrate it to version %d.\n", manifest.LockfileVersion)
+	}
 
-	// Check mode: compare with existing
-	if checkMode {
-		existing, err := os.ReadFile(outputFile)
-		if err != nil && !os.IsNotExist(err) {
-			return fmt.Errorf("reading existing lock file: %w", err)
+	if frozenMode {
+		return nil
+	}
+
+	// Report upstream changes, these are informational only
+	fmt.Fprintln(os.Stderr)
+	res := newResolution(m, lf)
+	var newer []string
+
+	components := m.AllComponents()
+	upstream := &manifest.Lockfile{Components: make(map[string]*manifest.ResolvedComponent)}
+	for _, name := range slices.Sorted(maps.Keys(components)) {
+		locked := lf.Components[name]
+		latest, err := res.resolveComponent(ctx, name, components[name])
+		if err != nil {
+			return err
+		}
+		upstream.Components[name] = latest
+		if latest.Version != locked.Version || latest.Build != locked.Build {
+			newer = append(newer, fmt.Sprintf("%s: %s -> %s", name,
+				formatVersion(locked.Version, locked.Build), formatVersion(latest.Version, latest.Build)))
 		}
+	}
+	res.followServer(upstream)
+
+	names := make([]string, 0, len(m.Plugins))
+	for name := range m.Plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-		if normalize(string(existing)) != normalize(content) {
-			fmt.Fprintln(os.Stderr, "\nLock file would change. Run without --check to update.")
-			os.Exit(1)
+	for _, name := range names {
+		latest, err := res.resolvePlugin(ctx, name, m.Plugins[name])
+		if err != nil {
+			return err
+		}
+		if locked := lf.Plugins[name]; latest.Version != locked.Version {
+			newer = append(newer, fmt.Sprintf("%s: %s -> %s", name, locked.Version, latest.Version))
 		}
-		fmt.Fprintln(os.Stderr, "\nLock file is up to date.")
-		return nil
 	}
 
-	// Write output
-	if err := os.WriteFile(outputFile, []byte(content), 0644); err != nil {
-		return fmt.Errorf("writing lock file: %w", err)
+	for _, kind := range packKinds {
+		packs := kind.config(m)
+		for _, name := range slices.Sorted(maps.Keys(packs)) {
+			latest, err := res.resolvePack(ctx, kind, name, packs[name])
+			if err != nil {
+				return err
+			}
+			if locked := kind.locked(lf)[name]; latest.Version != locked.Version {
+				newer = append(newer, fmt.Sprintf("%s: %s -> %s", name, locked.Version, latest.Version))
+			}
+		}
 	}
 
-	fmt.Fprintf(os.Stderr, "\nWrote %s\n", outputFile)
+	res.reportHeld()
+	if len(newer) == 0 {
+		fmt.Fprintln(os.Stderr, "\nNo newer upstream versions.")
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, "\nUpstream has newer versions (run 'scaf update <name>' to take them):")
+	for _, line := range newer {
+		fmt.Fprintf(os.Stderr, "  - %s\n", line)
+	}
 	return nil
 }
 
-// normalize removes timestamps for comparison
-func normalize(content string) string {
-	re := regexp.MustCompile(`resolved_at: .*`)
-	return re.ReplaceAllString(content, "resolved_at: <timestamp>")
+// resolvedAt returns the resolution time to record in lf, or the zero time
+// if lf is timestamp-free.
+func resolvedAt(lf *manifest.Lockfile) time.Time {
+	if withTimestamps || lf.Timestamped() {
+		return time.Now().UTC()
+	}
+	return time.Time{}
+}
+
+// resolverConfig converts a manifest plugin entry into resolver input,
+// applying the default source.
+func resolverConfig(plugin *manifest.PluginConfig) resolver.PluginConfig {
+	source := plugin.Source
+	if source == "" {
+		source = "hangar"
+	}
+
+	return resolver.PluginConfig{
+		Source:       source,
+		Project:      plugin.Project,
+		Version:      plugin.Version,
+		Platform:     plugin.Platform,
+		Loader:       plugin.Loader,
+		GameVersions: plugin.GameVersions,
+		Bucket:       plugin.Bucket,
+		Key:          plugin.Key,
+		URL:          plugin.URL,
+	}
+}
+
+// withGameVersions returns cfg restricted to the Paper version if settings
+// enable infer_game_versions, cfg has no game versions of its own and the
+// plugin runs on Paper.
+func withGameVersions(settings manifest.Settings, paper string, cfg resolver.PluginConfig) resolver.PluginConfig {
+	if !settings.InferGameVersions || paper == "" || len(cfg.GameVersions) > 0 || !runsOnPaper(cfg) {
+		return cfg
+	}
+	cfg.GameVersions = []string{paper}
+	return cfg
+}
+
+// runsOnPaper reports whether cfg selects a Paper plugin rather than a
+// proxy plugin, whose game versions are not Minecraft versions.
+func runsOnPaper(cfg resolver.PluginConfig) bool {
+	switch cfg.Source {
+	case "hangar":
+		return strings.EqualFold(cfg.Platform, "paper")
+	case "modrinth":
+		switch strings.ToLower(cfg.Loader) {
+		case "paper", "purpur", "folia", "spigot", "bukkit":
+			return true
+		}
+	}
+	return false
+}
+
+// jarFile returns where download puts the jar of a resolved entry,
+// relative to the output directory. dest overrides the settings.
+func jarFile(settings manifest.Settings, dest, name string, p *manifest.ResolvedPlugin) (string, error) {
+	if dest == "" {
+		dest = settings.Dest
+	}
+	return manifest.JarFile(dest, settings.Filename, name, p.Version, originalFilename(name, p))
+}
+
+// originalFilename returns the upstream file name of an entry, taken from
+// its download URL or S3 key.
+func originalFilename(name string, p *manifest.ResolvedPlugin) string {
+	var file string
+	if p.S3URI != "" {
+		file = path.Base(p.S3URI)
+	} else if u, err := url.Parse(p.URL); err == nil && p.URL != "" {
+		file = path.Base(u.Path)
+	}
+	if file == "" || file == "." || file == "/" {
+		return name + ".jar"
+	}
+	return file
+}
+
+// lockEntry converts a resolver result into a lockfile plugin entry.
+func lockEntry(result *resolver.Result) *manifest.ResolvedPlugin {
+	return &manifest.ResolvedPlugin{
+		Source:      result.Source,
+		Project:     result.Project,
+		Version:     result.Version,
+		PublishedAt: result.PublishedAt,
+		Platform:    result.Platform,
+		Loader:      result.Loader,
+		URL:         result.URL,
+		S3URI:       result.S3URI,
+		SHA256:      result.SHA256,
+		SHA512:      result.SHA512,
+	}
 }
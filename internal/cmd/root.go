@@ -20,9 +20,13 @@ var rootCmd = &cobra.Command{
 	Long: `scaf resolves and downloads Minecraft plugins from various sources.
 
 Supported sources:
+  - fabric    Fabric Meta (Fabric server launchers)
+  - forge     Minecraft Forge Maven (server installers)
   - hangar    PaperMC Hangar plugin repository
   - modrinth  Modrinth mod/plugin repository
+  - neoforge  NeoForged Maven (server installers)
   - papermc   PaperMC API (Velocity, Paper, etc.)
+  - purpur    Purpur API
   - s3        AWS S3 buckets
   - url       Direct URLs
 
@@ -50,4 +54,13 @@ func init() {
 	rootCmd.AddCommand(resolveCmd)
 	rootCmd.AddCommand(downloadCmd)
 	rootCmd.AddCommand(changelogCmd)
+	rootCmd.AddCommand(addCmd)
+	rootCmd.AddCommand(removeCmd)
+	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(outdatedCmd)
+	rootCmd.AddCommand(updateCmd)
+	rootCmd.AddCommand(treeCmd)
+	rootCmd.AddCommand(whyCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(propertiesCmd)
 }
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+)
+
+var treeFormat string
+
+var treeCmd = &cobra.Command{
+	Use:   "tree",
+	Short: "Show the plugin dependency graph",
+	Long: `Print the server, the plugins in the manifest and the plugins they
+depend on as a tree.
+
+Dependencies come from the lock file (transitive entries added by resolve)
+and, for jars already downloaded with 'scaf download', from their plugin.yml
+depend/softdepend lists and the equivalent paper-plugin.yml and
+velocity-plugin.json entries. Soft dependencies are marked (soft), plugins
+a descriptor depends on that are not locked are marked (missing), and
+subtrees already shown are marked (*).
+
+Output formats: text, dot (Graphviz)`,
+	Args: cobra.NoArgs,
+	RunE: runTree,
+}
+
+func init() {
+	treeCmd.Flags().StringVarP(&lockFile, "lockfile", "l", "plugins.lock.yaml", "Path to lock file")
+	treeCmd.Flags().StringVarP(&pluginsDir, "dir", "d", "./plugins", "Directory with downloaded plugin jars")
+	treeCmd.Flags().StringVarP(&treeFormat, "format", "f", "text", "Output format (text, dot)")
+}
+
+func runTree(cmd *cobra.Command, args []string) error {
+	switch treeFormat {
+	case "text", "dot":
+	default:
+		return fmt.Errorf("unknown format %q", treeFormat)
+	}
+
+	lf, err := manifest.LoadLockfile(lockFile)
+	if err != nil {
+		return err
+	}
+	g := buildGraph(lf, pluginsDir)
+
+	if treeFormat == "dot" {
+		writeTreeDot(os.Stdout, g)
+		return nil
+	}
+	writeTreeText(os.Stdout, g)
+	return nil
+}
+
+// serverLabel describes the server components of lf.
+func serverLabel(lf *manifest.Lockfile) string {
+	var parts []string
+	for _, name := range slices.Sorted(maps.Keys(lf.Components)) {
+		c := lf.Components[name]
+		label := componentLabel(name, c.Project) + " " + formatVersion(c.Version, c.Build)
+		if c.Loader != "" {
+			label += " (loader " + c.Loader + ")"
+		}
+		parts = append(parts, label)
+	}
+	if len(parts) == 0 {
+		return "server"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func writeTreeText(w io.Writer, g *depGraph) {
+	fmt.Fprintln(w, serverLabel(g.lf))
+
+	shown := make(map[string]bool)
+	var walk func(edges []depEdge, prefix string, path []string)
+	walk = func(edges []depEdge, prefix string, path []string) {
+		for i, e := range edges {
+			branch, indent := "├── ", "│   "
+			if i == len(edges)-1 {
+				branch, indent = "└── ", "    "
+			}
+
+			line := g.label(e.to)
+			if e.soft {
+				line += " (soft)"
+			}
+			if e.missing {
+				line += " (missing)"
+			}
+			if (shown[e.to] || slices.Contains(path, e.to)) && len(g.edges[e.to]) > 0 {
+				fmt.Fprintf(w, "%s%s%s (*)\n", prefix, branch, line)
+				continue
+			}
+			fmt.Fprintf(w, "%s%s%s\n", prefix, branch, line)
+			shown[e.to] = true
+			walk(g.edges[e.to], prefix+indent, append(path, e.to))
+		}
+	}
+
+	// The manifest entries hang off the server
+	var roots []depEdge
+	for _, name := range g.roots() {
+		roots = append(roots, depEdge{to: name})
+	}
+	walk(roots, "", nil)
+}
+
+func writeTreeDot(w io.Writer, g *depGraph) {
+	fmt.Fprintln(w, "digraph plugins {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintf(w, "  server [label=%q, shape=box];\n", serverLabel(g.lf))
+
+	nodes := make(map[string]bool)
+	node := func(name string) {
+		if nodes[name] {
+			return
+		}
+		nodes[name] = true
+		attrs := fmt.Sprintf("label=%q", g.label(name))
+		if p, ok := g.lf.Plugins[name]; !ok {
+			attrs += ", color=red, fontcolor=red"
+		} else if p.Transitive {
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(w, "  %q [%s];\n", name, attrs)
+	}
+
+	for _, name := range g.roots() {
+		node(name)
+		fmt.Fprintf(w, "  server -> %q;\n", name)
+	}
+	for _, name := range g.sources() {
+		node(name)
+		for _, e := range g.edges[name] {
+			node(e.to)
+			attrs := ""
+			if e.soft {
+				attrs = " [style=dashed]"
+			}
+			fmt.Fprintf(w, "  %q -> %q%s;\n", name, e.to, attrs)
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
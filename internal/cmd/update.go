@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+	"github.com/PrimCraft/scaf/internal/resolver"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update [name...]",
+	Short: "Re-resolve selected lock file entries",
+	Long: `Re-resolve only the named entries of the lock file, leaving all other
+entries untouched. Server components are updated by their name, such as
+"velocity" or "paper", and datapacks and resource packs by their name.
+
+Without arguments, only entries that are out of sync with the manifest are
+updated: entries that were added, removed, or whose source or constraint
+changed since they were locked.
+
+Required dependencies of the updated entries are added to the lock file as
+transitive entries, and transitive entries nothing requires are removed.`,
+	RunE: runUpdate,
+}
+
+func init() {
+	updateCmd.Flags().StringVarP(&manifestFile, "manifest", "m", "plugins.yaml", "Path to manifest file")
+	updateCmd.Flags().StringVarP(&lockFile, "lockfile", "l", "plugins.lock.yaml", "Path to lock file")
+	updateCmd.Flags().BoolVar(&withTimestamps, "timestamps", false, "Record resolution times in the lock file")
+	updateCmd.Flags().BoolVar(&allowMajor, "allow-major", false, "Ignore max_bump and take any version the constraints allow")
+}
+
+func runUpdate(cmd *cobra.Command, args []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	m, err := manifest.Load(manifestFile)
+	if err != nil {
+		return err
+	}
+	lf, err := manifest.LoadLockfile(lockFile)
+	if err != nil {
+		return err
+	}
+
+	digest, err := m.Digest()
+	if err != nil {
+		return err
+	}
+
+	targets := args
+	if len(targets) == 0 {
+		targets = staleEntries(m, lf)
+		if len(targets) == 0 && lf.ManifestDigest == digest && !lf.Migrated() {
+			fmt.Fprintln(os.Stderr, "Lock file is in sync with the manifest.")
+			return nil
+		}
+	}
+
+	res := newResolution(m, lf)
+	now := resolvedAt(lf)
+	for _, name := range targets {
+		if err := updateEntry(ctx, res, m, lf, name); err != nil {
+			return err
+		}
+		if p, ok := lf.Plugins[name]; ok {
+			p.ResolvedAt = now
+		}
+	}
+
+	// With infer_game_versions, plugins follow a Paper update
+	components := m.AllComponents()
+	updatesServer := slices.ContainsFunc(targets, func(name string) bool {
+		c, ok := components[name]
+		return ok && componentPlatform(c.Project) == "paper"
+	})
+	if m.Settings.InferGameVersions && updatesServer {
+		for _, name := range staleEntries(m, lf) {
+			if _, ok := m.Plugins[name]; !ok || slices.Contains(targets, name) {
+				continue
+			}
+			if err := updateEntry(ctx, res, m, lf, name); err != nil {
+				return err
+			}
+			lf.Plugins[name].ResolvedAt = now
+		}
+	}
+	if err := res.resolveDependencies(ctx, lf); err != nil {
+		return err
+	}
+	pruneTransitive(lf)
+	if problems := checkConflicts(lf); len(problems) > 0 {
+		return conflictError(problems)
+	}
+
+	lf.ResolvedAt = now
+	setDigest(m, lf, digest)
+	if err := lf.Write(lockFile); err != nil {
+		return err
+	}
+
+	res.reportHeld()
+	fmt.Fprintf(os.Stderr, "\nWrote %s\n", lockFile)
+	return nil
+}
+
+// updateEntry re-resolves a single entry in place, or drops it from the
+// lock file if it is no longer in the manifest.
+func updateEntry(ctx context.Context, res *resolution, m *manifest.Manifest, lf *manifest.Lockfile, name string) error {
+	if c, ok := m.AllComponents()[name]; ok {
+		resolved, err := res.resolveComponent(ctx, name, c)
+		if err != nil {
+			return err
+		}
+		lf.Components[name] = resolved
+		res.followServer(lf)
+		return nil
+	}
+	if _, ok := lf.Components[name]; ok {
+		fmt.Fprintf(os.Stderr, "Removing %s (no longer in manifest)\n", name)
+		delete(lf.Components, name)
+		res.followServer(lf)
+		return nil
+	}
+
+	// A name moved between sections is resolved in one and removed from
+	// the other
+	var found bool
+	for _, kind := range packKinds {
+		locked := kind.locked(lf)
+		if p, ok := kind.config(m)[name]; ok {
+			resolved, err := res.resolvePack(ctx, kind, name, p)
+			if err != nil {
+				return err
+			}
+			locked[name] = resolved
+			found = true
+		} else if _, ok := locked[name]; ok {
+			fmt.Fprintf(os.Stderr, "Removing %s (no longer in manifest)\n", name)
+			delete(locked, name)
+			found = true
+		}
+	}
+	if found && lf.Plugins[name] == nil {
+		return nil
+	}
+
+	// Its dependencies are queued again when it is resolved
+	dropRequirer(lf, name)
+
+	plugin, ok := m.Plugins[name]
+	if !ok {
+		locked, isLocked := lf.Plugins[name]
+		if !isLocked {
+			return fmt.Errorf("%s is not in the manifest", name)
+		}
+		if locked.Transitive && required(lf, locked) {
+			resolved, err := res.resolve(ctx, name, transitiveConfig(locked), manifest.Policy{})
+			if err != nil {
+				return err
+			}
+			resolved.Constraint = locked.Constraint
+			resolved.GameVersions = locked.GameVersions
+			resolved.Transitive = true
+			resolved.RequiredBy = locked.RequiredBy
+			lf.Plugins[name] = resolved
+			return nil
+		}
+		fmt.Fprintf(os.Stderr, "Removing %s (no longer in manifest)\n", name)
+		delete(lf.Plugins, name)
+		return nil
+	}
+
+	resolved, err := res.resolvePlugin(ctx, name, plugin)
+	if err != nil {
+		return err
+	}
+	lf.Plugins[name] = resolved
+	return nil
+}
+
+// staleEntries returns the names of lock file entries that are out of sync
+// with the manifest.
+func staleEntries(m *manifest.Manifest, lf *manifest.Lockfile) []string {
+	var stale []string
+
+	components := m.AllComponents()
+	for name, c := range components {
+		if locked, ok := lf.Components[name]; !ok || componentStale(c, locked) {
+			stale = append(stale, name)
+		}
+	}
+	for name := range lf.Components {
+		if _, ok := components[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+
+	var paper string
+	if server := paperServer(lf); server != nil {
+		paper = server.Version
+	}
+
+	var plugins []string
+	for name, plugin := range m.Plugins {
+		locked, ok := lf.Plugins[name]
+		if !ok || pluginStale(withGameVersions(m.Settings, paper, resolverConfig(plugin)), locked) ||
+			fileStale(m.Settings, plugin.Dest, name, locked) {
+			plugins = append(plugins, name)
+		}
+	}
+	for name, locked := range lf.Plugins {
+		if _, ok := m.Plugins[name]; ok {
+			continue
+		}
+		// Transitive entries stay as long as something requires them
+		if !locked.Transitive || !required(lf, locked) || fileStale(m.Settings, "", name, locked) {
+			plugins = append(plugins, name)
+		}
+	}
+	for _, kind := range packKinds {
+		packs, locked := kind.config(m), kind.locked(lf)
+		for name, p := range packs {
+			if l, ok := locked[name]; !ok || packStale(m.Settings, paper, kind, name, p, l) {
+				plugins = append(plugins, name)
+			}
+		}
+		for name := range locked {
+			if _, ok := packs[name]; !ok {
+				plugins = append(plugins, name)
+			}
+		}
+	}
+	sort.Strings(plugins)
+
+	return append(stale, slices.Compact(plugins)...)
+}
+
+// setDigest records the manifest digest, but only once every entry is in
+// sync, so a partial update does not hide remaining drift.
+func setDigest(m *manifest.Manifest, lf *manifest.Lockfile, digest string) {
+	if len(staleEntries(m, lf)) == 0 {
+		lf.ManifestDigest = digest
+	}
+}
+
+// fileStale reports whether a locked plugin is no longer downloaded to the
+// path the manifest's dest and filename settings give it.
+func fileStale(settings manifest.Settings, dest, name string, locked *manifest.ResolvedPlugin) bool {
+	file, err := jarFile(settings, dest, name, locked)
+	return err != nil || file != pluginFile(name, locked)
+}
+
+// pluginStale reports whether a locked plugin no longer matches the
+// resolver input of its manifest entry.
+func pluginStale(cfg resolver.PluginConfig, locked *manifest.ResolvedPlugin) bool {
+	if locked.Transitive || cfg.Source != locked.Source {
+		return true
+	}
+
+	switch cfg.Source {
+	case "hangar", "modrinth":
+		if cfg.Project != locked.Project {
+			return true
+		}
+	case "url":
+		if cfg.URL != locked.URL {
+			return true
+		}
+	case "s3":
+		key := strings.ReplaceAll(cfg.Key, "${version}", locked.Version)
+		if fmt.Sprintf("s3://%s/%s", cfg.Bucket, key) != locked.S3URI {
+			return true
+		}
+	}
+	if cfg.Platform != "" && cfg.Platform != locked.Platform {
+		return true
+	}
+	if cfg.Loader != "" && cfg.Loader != locked.Loader {
+		return true
+	}
+
+	// Lock files written before constraints were recorded have none
+	if locked.Constraint != "" && locked.Constraint != cfg.Version {
+		return true
+	}
+	if locked.Constraint != "" && !slices.Equal(locked.GameVersions, cfg.GameVersions) {
+		return true
+	}
+
+	return !resolver.Satisfies(locked.Version, cfg.Version)
+}
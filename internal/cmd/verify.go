@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PrimCraft/scaf/internal/jar"
+	"github.com/PrimCraft/scaf/internal/manifest"
+)
+
+var verifyFormat string
+
+// Verify statuses. Everything but ok and unverified fails the check.
+const (
+	verifyOK         = "ok"
+	verifyUnverified = "unverified"
+	verifyMissing    = "missing"
+	verifyModified   = "modified"
+	verifyExtra      = "extra"
+	verifyUnlocked   = "unlocked"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check the jars in a plugins folder against the lock file",
+	Long: `Hash every jar in the plugins folder, and in the directories lock file
+entries are downloaded to, and match it to the lock file by SHA-256 or
+SHA-512; server jars are matched by path and MD5 or SHA-1. Each jar and
+lock file entry gets a status:
+
+  ok          the jar matches a locked entry
+  unverified  the jar is at the entry's path, but the lock file has no
+              hash for it (url and s3 sources, most server jars)
+  missing     no jar matches a locked entry
+  modified    the jar at an entry's path does not match the locked hash
+  extra       a second copy of a locked plugin, or another version of it
+  unlocked    a jar that is not in the lock file
+
+The command exits 1 if any jar or entry is missing, modified, extra or
+unlocked. Output formats: text, json`,
+	Args: cobra.NoArgs,
+	RunE: runVerify,
+}
+
+func init() {
+	verifyCmd.Flags().StringVarP(&lockFile, "lockfile", "l", "plugins.lock.yaml", "Path to lock file")
+	verifyCmd.Flags().StringVarP(&pluginsDir, "dir", "d", "./plugins", "Plugins directory to verify")
+	verifyCmd.Flags().StringVarP(&verifyFormat, "format", "f", "text", "Output format (text, json)")
+}
+
+// verifyResult is the status of one jar or lock file entry.
+type verifyResult struct {
+	Status   string `json:"status"`
+	Name     string `json:"name,omitempty"`
+	File     string `json:"file,omitempty"`
+	Detail   string `json:"detail,omitempty"`
+	Expected string `json:"expected_sha256,omitempty"`
+	Actual   string `json:"actual_sha256,omitempty"`
+
+	// Entries locked with only a SHA-512 are compared by it instead
+	ExpectedSHA512 string `json:"expected_sha512,omitempty"`
+	ActualSHA512   string `json:"actual_sha512,omitempty"`
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	switch verifyFormat {
+	case "text", "json":
+	default:
+		return fmt.Errorf("unknown format %q", verifyFormat)
+	}
+
+	lf, err := manifest.LoadLockfile(lockFile)
+	if err != nil {
+		return err
+	}
+	files, err := verifyFiles(lf, pluginsDir)
+	if err != nil {
+		return err
+	}
+
+	results, err := verifyJars(lf, pluginsDir, files)
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Status != verifyOK && r.Status != verifyUnverified {
+			failed++
+		}
+	}
+
+	if verifyFormat == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if results == nil {
+			results = []verifyResult{}
+		}
+		if err := enc.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		writeVerifyTable(os.Stdout, results)
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "\n%s does not match %s (%d problems)\n", pluginsDir, lockFile, failed)
+		os.Exit(1)
+	}
+	fmt.Fprintf(os.Stderr, "\n%s matches %s\n", pluginsDir, lockFile)
+	return nil
+}
+
+// verifyFiles lists the jars in dir and in the directories lock file
+// entries are downloaded to.
+func verifyFiles(lf *manifest.Lockfile, dir string) ([]string, error) {
+	dirs := map[string]bool{".": true}
+	for _, c := range lf.Components {
+		dirs[path.Dir(c.File)] = true
+	}
+	for name, p := range lf.Plugins {
+		dirs[path.Dir(pluginFile(name, p))] = true
+	}
+
+	var files []string
+	for _, d := range slices.Sorted(maps.Keys(dirs)) {
+		if !filepath.IsLocal(filepath.FromSlash(d)) {
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(d), "*.jar"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	return files, nil
+}
+
+// verifyJars matches the jars in dir against lf.
+func verifyJars(lf *manifest.Lockfile, dir string, files []string) ([]verifyResult, error) {
+	// Server jars are downloaded next to the plugins without hashes
+	var entries []string
+	components := make(map[string]string)
+	for _, name := range slices.Sorted(maps.Keys(lf.Components)) {
+		c := lf.Components[name]
+		entries = append(entries, name)
+		components[path.Clean(c.File)] = name
+	}
+	entries = append(entries, sortedPlugins(lf)...)
+
+	byHash := make(map[string]string)
+	byFile := make(map[string]string)
+	known := make(map[string]string)
+	for name, p := range lf.Plugins {
+		if p.SHA256 != "" {
+			byHash[p.SHA256] = name
+		}
+		if p.SHA512 != "" {
+			byHash[p.SHA512] = name
+		}
+		byFile[path.Clean(pluginFile(name, p))] = name
+		known[strings.ToLower(name)] = name
+		if p.Declared != nil {
+			known[strings.ToLower(p.Declared.Name)] = name
+		}
+	}
+
+	// Entries are keyed by their path relative to dir
+	rels := make([]string, len(files))
+	for i, file := range files {
+		rels[i] = file
+		if r, err := filepath.Rel(dir, file); err == nil {
+			rels[i] = filepath.ToSlash(r)
+		}
+	}
+
+	// Jars at an entry's path claim it before copies elsewhere, results
+	// keep the order of files
+	order := make([]int, len(files))
+	for i := range order {
+		order[i] = i
+	}
+	atEntryPath := func(i int) bool { return components[rels[i]] != "" || byFile[rels[i]] != "" }
+	sort.SliceStable(order, func(a, b int) bool { return atEntryPath(order[a]) && !atEntryPath(order[b]) })
+
+	fileResults := make([]verifyResult, len(files))
+	matched := make(map[string]string)
+	for _, i := range order {
+		file, rel := files[i], rels[i]
+		if name, ok := components[rel]; ok {
+			matched[name] = file
+			result := verifyResult{Status: verifyOK, Name: name, File: file}
+			switch err := checkComponent(file, lf.Components[name]); {
+			case !componentHashed(lf.Components[name]):
+				result.Status, result.Detail = verifyUnverified, "server jar, no hash in lock file"
+			case err != nil:
+				result.Status, result.Detail = verifyModified, err.Error()
+			}
+			fileResults[i] = result
+			continue
+		}
+
+		info, err := jar.Sum(file)
+		if err != nil {
+			return nil, fmt.Errorf("hashing %s: %w", file, err)
+		}
+		result := verifyResult{File: file, Actual: info.SHA256}
+
+		name, ok := byHash[info.SHA256]
+		if !ok {
+			name, ok = byHash[info.SHA512]
+		}
+		switch {
+		case ok && matched[name] != "":
+			result.Status, result.Name = verifyExtra, name
+			result.Detail = "copy of " + matched[name]
+		case ok:
+			result.Status, result.Name = verifyOK, name
+			matched[name] = file
+		case byFile[rel] != "":
+			name = byFile[rel]
+			p := lf.Plugins[name]
+			result.Name = name
+			matched[name] = file
+			if p.SHA256 == "" && p.SHA512 == "" {
+				result.Status, result.Detail = verifyUnverified, "no hash in lock file"
+				break
+			}
+			result.Status, result.Detail = verifyModified, "hash does not match the lock file"
+			expectHash(&result, p)
+			if result.Expected == "" {
+				result.Actual, result.ActualSHA512 = "", info.SHA512
+			}
+		default:
+			result.Status, result.Detail = verifyUnlocked, "not in lock file"
+			if info, err := jar.Inspect(file); err == nil && info.Primary() != nil {
+				d := info.Primary()
+				result.Detail = fmt.Sprintf("declares %s %s", d.Name, d.Version)
+				if name, ok := known[strings.ToLower(d.Name)]; ok {
+					result.Status, result.Name = verifyExtra, name
+					result.Detail = fmt.Sprintf("another version of %s (declares %s %s)", name, d.Name, d.Version)
+				}
+			}
+		}
+		fileResults[i] = result
+	}
+	results := fileResults
+
+	for _, name := range entries {
+		if _, ok := matched[name]; ok {
+			continue
+		}
+		result := verifyResult{Status: verifyMissing, Name: name}
+		if c, ok := lf.Components[name]; ok {
+			result.File, _ = componentPath(dir, c)
+		}
+		if p, ok := lf.Plugins[name]; ok {
+			result.File, _ = jarPath(dir, name, p)
+			result.Detail = "locked " + p.Version
+			expectHash(&result, p)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// expectHash sets the hash r expects from the lock file entry p: its SHA-256,
+// or its SHA-512 if it has none.
+func expectHash(r *verifyResult, p *manifest.ResolvedPlugin) {
+	if p.SHA256 != "" {
+		r.Expected = p.SHA256
+		return
+	}
+	r.ExpectedSHA512 = p.SHA512
+}
+
+func writeVerifyTable(w io.Writer, results []verifyResult) {
+	if len(results) == 0 {
+		fmt.Fprintln(w, "No jars and nothing locked")
+		return
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STATUS\tNAME\tFILE\tDETAIL")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.Status, orDash(r.Name), r.File, r.Detail)
+	}
+	_ = tw.Flush()
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/PrimCraft/scaf/internal/manifest"
+)
+
+var whyCmd = &cobra.Command{
+	Use:   "why <plugin>",
+	Short: "Explain why a plugin is in the lock file",
+	Long: `Show the chains of dependencies that lead from manifest entries to a
+plugin, using the same dependency data as 'scaf tree'.
+
+Each step names where the dependency was declared: the lock file, for
+dependencies added by resolve, or the descriptor of a downloaded jar.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runWhy,
+}
+
+func init() {
+	whyCmd.Flags().StringVarP(&lockFile, "lockfile", "l", "plugins.lock.yaml", "Path to lock file")
+	whyCmd.Flags().StringVarP(&pluginsDir, "dir", "d", "./plugins", "Directory with downloaded plugin jars")
+}
+
+func runWhy(cmd *cobra.Command, args []string) error {
+	lf, err := manifest.LoadLockfile(lockFile)
+	if err != nil {
+		return err
+	}
+	g := buildGraph(lf, pluginsDir)
+
+	target := lookupEntry(lf, args[0])
+	paths := g.pathsTo(target)
+	locked, ok := lf.Plugins[target]
+
+	switch {
+	case ok && !locked.Transitive:
+		fmt.Printf("%s is in the manifest\n", g.label(target))
+	case ok:
+		fmt.Printf("%s is a transitive dependency\n", g.label(target))
+	case len(paths) > 0:
+		fmt.Printf("%s is not in the lock file\n", target)
+	default:
+		return fmt.Errorf("%s is not in the lock file and nothing depends on it", target)
+	}
+
+	if len(paths) == 0 {
+		if ok && locked.Transitive {
+			fmt.Println("Nothing requires it anymore, run 'scaf update' to remove it.")
+		}
+		return nil
+	}
+	fmt.Println("\nDepended on by:")
+	for _, path := range paths {
+		fmt.Printf("  %s\n", path)
+	}
+
+	if !ok {
+		fmt.Println("\nAdd it to the manifest to install it.")
+	}
+	return nil
+}
+
+// pathsTo returns every dependency chain from a manifest entry to target,
+// formatted for output.
+func (g *depGraph) pathsTo(target string) []string {
+	// Invert the graph to walk from target up to the roots
+	parents := make(map[string][]string)
+	edgeTo := make(map[[2]string]depEdge)
+	for _, from := range g.sources() {
+		for _, e := range g.edges[from] {
+			parents[e.to] = append(parents[e.to], from)
+			edgeTo[[2]string{from, e.to}] = e
+		}
+	}
+
+	var paths []string
+	var walk func(name string, chain []string)
+	walk = func(name string, chain []string) {
+		chain = append([]string{name}, chain...)
+		if p, ok := g.lf.Plugins[name]; ok && !p.Transitive && len(chain) > 1 {
+			paths = append(paths, formatChain(chain, edgeTo))
+		}
+		for _, parent := range parents[name] {
+			if !slices.Contains(chain, parent) {
+				walk(parent, chain)
+			}
+		}
+	}
+	walk(target, nil)
+	return paths
+}
+
+// formatChain formats a chain like "tab -> luckperms (plugin.yml, soft)".
+func formatChain(chain []string, edgeTo map[[2]string]depEdge) string {
+	var b strings.Builder
+	b.WriteString(chain[0])
+	for i := 1; i < len(chain); i++ {
+		e := edgeTo[[2]string{chain[i-1], chain[i]}]
+		via := e.via
+		if e.soft {
+			via += ", soft"
+		}
+		fmt.Fprintf(&b, " -> %s (%s)", chain[i], via)
+	}
+	return b.String()
+}
+
+// lookupEntry returns the lock file entry named name, or failing that one
+// whose name matches it case-insensitively. Plugins that are not locked are
+// known by their lowercased declared name.
+func lookupEntry(lf *manifest.Lockfile, name string) string {
+	if _, ok := lf.Plugins[name]; ok {
+		return name
+	}
+	for locked := range lf.Plugins {
+		if strings.EqualFold(locked, name) {
+			return locked
+		}
+	}
+	return strings.ToLower(name)
+}
@@ -0,0 +1,365 @@
+// Package jar inspects plugin jars and their descriptors.
+package jar
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Platform identifies the plugin API a descriptor targets.
+type Platform string
+
+const (
+	PlatformBukkit   Platform = "bukkit"   // plugin.yml
+	PlatformPaper    Platform = "paper"    // paper-plugin.yml
+	PlatformVelocity Platform = "velocity" // velocity-plugin.json
+	PlatformBungee   Platform = "bungee"   // bungee.yml
+	PlatformFabric   Platform = "fabric"   // fabric.mod.json
+)
+
+// Descriptor is a plugin descriptor found inside a jar.
+type Descriptor struct {
+	Platform Platform `json:"platform" yaml:"platform"`
+	File     string   `json:"file" yaml:"file"`
+	Name     string   `json:"name" yaml:"name"`
+	Version  string   `json:"version,omitempty" yaml:"version,omitempty"`
+	Main     string   `json:"main,omitempty" yaml:"main,omitempty"`
+
+	// ID is the Velocity plugin id, which dependencies refer to.
+	ID string `json:"id,omitempty" yaml:"id,omitempty"`
+
+	// APIVersion is the oldest Bukkit API version the plugin supports.
+	APIVersion string `json:"api_version,omitempty" yaml:"api_version,omitempty"`
+
+	// Depend and SoftDepend name the plugins this one requires or loads
+	// after if present.
+	Depend     []string `json:"depend,omitempty" yaml:"depend,omitempty"`
+	SoftDepend []string `json:"softdepend,omitempty" yaml:"softdepend,omitempty"`
+
+	// LoadBefore names plugins that must load after this one, and
+	// Provides the other plugin names this one can stand in for.
+	LoadBefore []string `json:"loadbefore,omitempty" yaml:"loadbefore,omitempty"`
+	Provides   []string `json:"provides,omitempty" yaml:"provides,omitempty"`
+}
+
+// Info is the result of inspecting a jar.
+type Info struct {
+	Path        string       `json:"path"`
+	Descriptors []Descriptor `json:"descriptors"`
+	SHA1        string       `json:"sha1"`
+	SHA256      string       `json:"sha256"`
+	SHA512      string       `json:"sha512"`
+}
+
+// Primary returns the descriptor that best identifies the plugin, or nil
+// if the jar has no plugin descriptor.
+func (i *Info) Primary() *Descriptor {
+	if len(i.Descriptors) == 0 {
+		return nil
+	}
+	return &i.Descriptors[0]
+}
+
+// Find returns the first descriptor for one of the given platforms, or nil
+// if there is none.
+func (i *Info) Find(platforms ...Platform) *Descriptor {
+	for j, d := range i.Descriptors {
+		for _, p := range platforms {
+			if d.Platform == p {
+				return &i.Descriptors[j]
+			}
+		}
+	}
+	return nil
+}
+
+// Has reports whether the jar contains a descriptor for the given platform.
+func (i *Info) Has(platform Platform) bool {
+	for _, d := range i.Descriptors {
+		if d.Platform == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// descriptorFiles lists known descriptor files in order of preference.
+var descriptorFiles = []struct {
+	name     string
+	platform Platform
+	parse    func([]byte) (*Descriptor, error)
+}{
+	{"paper-plugin.yml", PlatformPaper, parsePaper},
+	{"plugin.yml", PlatformBukkit, parseBukkit},
+	{"velocity-plugin.json", PlatformVelocity, parseVelocity},
+	{"bungee.yml", PlatformBungee, parseBukkit},
+	{"fabric.mod.json", PlatformFabric, parseFabric},
+}
+
+// Inspect hashes the jar at path and parses its plugin descriptors.
+func Inspect(path string) (*Info, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	info := sum(path, data)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("opening jar: %w", err)
+	}
+
+	files := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	for _, df := range descriptorFiles {
+		f, ok := files[df.name]
+		if !ok {
+			continue
+		}
+		raw, err := readFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", df.name, err)
+		}
+		d, err := df.parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", df.name, err)
+		}
+		d.Platform = df.platform
+		d.File = df.name
+		info.Descriptors = append(info.Descriptors, *d)
+	}
+
+	return info, nil
+}
+
+// Sum hashes the file at path without parsing it, for files that may not
+// be valid jars.
+func Sum(path string) (*Info, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return sum(path, data), nil
+}
+
+func sum(path string, data []byte) *Info {
+	sum1 := sha1.Sum(data)
+	sum256 := sha256.Sum256(data)
+	sum512 := sha512.Sum512(data)
+	return &Info{
+		Path:   path,
+		SHA1:   hex.EncodeToString(sum1[:]),
+		SHA256: hex.EncodeToString(sum256[:]),
+		SHA512: hex.EncodeToString(sum512[:]),
+	}
+}
+
+func readFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	return io.ReadAll(rc)
+}
+
+type bukkitDescriptor struct {
+	Name       scalar `yaml:"name"`
+	Version    scalar `yaml:"version"`
+	Main       scalar `yaml:"main"`
+	APIVersion scalar `yaml:"api-version"`
+	Depend     list   `yaml:"depend"`
+	SoftDepend list   `yaml:"softdepend"`
+	LoadBefore list   `yaml:"loadbefore"`
+	Provides   list   `yaml:"provides"`
+}
+
+func parseBukkit(data []byte) (*Descriptor, error) {
+	var raw bukkitDescriptor
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	return &Descriptor{
+		Name:       string(raw.Name),
+		Version:    string(raw.Version),
+		Main:       string(raw.Main),
+		APIVersion: string(raw.APIVersion),
+		Depend:     raw.Depend,
+		SoftDepend: raw.SoftDepend,
+		LoadBefore: raw.LoadBefore,
+		Provides:   raw.Provides,
+	}, nil
+}
+
+// paperDescriptor is a paper-plugin.yml. Server dependencies are a map
+// from plugin name to options; required defaults to true, and load AFTER
+// means the dependency loads after this plugin.
+type paperDescriptor struct {
+	Name         scalar   `yaml:"name"`
+	Version      scalar   `yaml:"version"`
+	Main         scalar   `yaml:"main"`
+	APIVersion   scalar   `yaml:"api-version"`
+	Provides     []string `yaml:"provides"`
+	Dependencies struct {
+		Server map[string]struct {
+			Required *bool  `yaml:"required"`
+			Load     string `yaml:"load"`
+		} `yaml:"server"`
+	} `yaml:"dependencies"`
+}
+
+func parsePaper(data []byte) (*Descriptor, error) {
+	var raw paperDescriptor
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	d := &Descriptor{
+		Name:       string(raw.Name),
+		Version:    string(raw.Version),
+		Main:       string(raw.Main),
+		APIVersion: string(raw.APIVersion),
+		Provides:   raw.Provides,
+	}
+	for name, dep := range raw.Dependencies.Server {
+		if dep.Required == nil || *dep.Required {
+			d.Depend = append(d.Depend, name)
+		} else {
+			d.SoftDepend = append(d.SoftDepend, name)
+		}
+		if strings.EqualFold(dep.Load, "after") {
+			d.LoadBefore = append(d.LoadBefore, name)
+		}
+	}
+	sort.Strings(d.Depend)
+	sort.Strings(d.SoftDepend)
+	sort.Strings(d.LoadBefore)
+	return d, nil
+}
+
+type velocityDescriptor struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	Version      string `json:"version"`
+	Main         string `json:"main"`
+	Dependencies []struct {
+		ID       string `json:"id"`
+		Optional bool   `json:"optional"`
+	} `json:"dependencies"`
+}
+
+func parseVelocity(data []byte) (*Descriptor, error) {
+	var raw velocityDescriptor
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	name := raw.Name
+	if name == "" {
+		name = raw.ID
+	}
+	d := &Descriptor{
+		Name:    name,
+		ID:      raw.ID,
+		Version: raw.Version,
+		Main:    raw.Main,
+	}
+	for _, dep := range raw.Dependencies {
+		if dep.Optional {
+			d.SoftDepend = append(d.SoftDepend, dep.ID)
+		} else {
+			d.Depend = append(d.Depend, dep.ID)
+		}
+	}
+	return d, nil
+}
+
+// fabricDescriptor is a fabric.mod.json. Other mods refer to a mod by its
+// id, so that is used as the name. Dependency values are version ranges.
+type fabricDescriptor struct {
+	ID         string                     `json:"id"`
+	Version    string                     `json:"version"`
+	Provides   []string                   `json:"provides"`
+	Depends    map[string]json.RawMessage `json:"depends"`
+	Recommends map[string]json.RawMessage `json:"recommends"`
+	Suggests   map[string]json.RawMessage `json:"suggests"`
+}
+
+// fabricBuiltins are dependency ids the loader itself provides.
+var fabricBuiltins = map[string]bool{"minecraft": true, "java": true, "fabricloader": true}
+
+func parseFabric(data []byte) (*Descriptor, error) {
+	var raw fabricDescriptor
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	d := &Descriptor{
+		Name:     raw.ID,
+		Version:  raw.Version,
+		Provides: raw.Provides,
+	}
+	ids := func(deps map[string]json.RawMessage) []string {
+		var out []string
+		for id := range deps {
+			if !fabricBuiltins[id] {
+				out = append(out, id)
+			}
+		}
+		sort.Strings(out)
+		return out
+	}
+	d.Depend = ids(raw.Depends)
+	d.SoftDepend = append(ids(raw.Recommends), ids(raw.Suggests)...)
+	return d, nil
+}
+
+// list decodes a YAML list of strings, or a single string as a list of
+// one, which Bukkit also accepts.
+type list []string
+
+func (l *list) UnmarshalYAML(b []byte) error {
+	var items []string
+	if err := yaml.Unmarshal(b, &items); err == nil {
+		*l = items
+		return nil
+	}
+	var item scalar
+	if err := item.UnmarshalYAML(b); err != nil {
+		return err
+	}
+	if item != "" && item != "null" && item != "~" {
+		*l = list{string(item)}
+	}
+	return nil
+}
+
+// scalar decodes any YAML scalar as its literal text, so that versions
+// like 1.10 are not mangled into floats.
+type scalar string
+
+func (s *scalar) UnmarshalYAML(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if len(b) > 0 && (b[0] == '"' || b[0] == '\'') {
+		var str string
+		if err := yaml.Unmarshal(b, &str); err != nil {
+			return err
+		}
+		*s = scalar(str)
+		return nil
+	}
+	*s = scalar(b)
+	return nil
+}
@@ -0,0 +1,238 @@
+package manifest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/ast"
+	"github.com/goccy/go-yaml/parser"
+	"github.com/goccy/go-yaml/token"
+)
+
+// AddPlugin inserts a plugin entry into the raw manifest YAML.
+// The document is edited through its AST so existing comments and key
+// ordering are preserved.
+func AddPlugin(data []byte, name string, p *PluginConfig) ([]byte, error) {
+	file, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+
+	entry, err := yaml.Marshal(map[string]*PluginConfig{name: p})
+	if err != nil {
+		return nil, fmt.Errorf("marshaling plugin: %w", err)
+	}
+
+	root, err := rootMapping(file)
+	if err != nil {
+		return nil, err
+	}
+
+	section := findKey(root, "plugins")
+	if section == nil {
+		// No plugins section yet, append one to the end of the document
+		node, err := parseNode(append([]byte("plugins:\n"), indent(entry)...))
+		if err != nil {
+			return nil, err
+		}
+		root.Values = append(root.Values, node.Values...)
+		return render(file), nil
+	}
+
+	plugins, ok := section.Value.(*ast.MappingNode)
+	if !ok {
+		// "plugins:" with no entries parses as null
+		if _, isNull := section.Value.(*ast.NullNode); !isNull {
+			return nil, fmt.Errorf("manifest plugins section is not a mapping")
+		}
+		node, err := parseNode(append([]byte("plugins:\n"), indent(entry)...))
+		if err != nil {
+			return nil, err
+		}
+		// The inline comment of a null value belongs on the key once it
+		// holds a mapping
+		if comment := section.Value.GetComment(); comment != nil && section.Key.GetComment() == nil {
+			_ = section.Key.SetComment(comment)
+		}
+		section.Value = node.Values[0].Value
+		return render(file), nil
+	}
+
+	if findKey(plugins, name) != nil {
+		return nil, fmt.Errorf("plugin %q already exists in manifest", name)
+	}
+
+	node, err := parseNode(entry)
+	if err != nil {
+		return nil, err
+	}
+	plugins.Merge(node)
+
+	return render(file), nil
+}
+
+// RemovePlugin deletes a plugin entry from the raw manifest YAML,
+// preserving the rest of the document.
+func RemovePlugin(data []byte, name string) ([]byte, error) {
+	file, err := parser.ParseBytes(data, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+
+	root, err := rootMapping(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var plugins *ast.MappingNode
+	if section := findKey(root, "plugins"); section != nil {
+		plugins, _ = section.Value.(*ast.MappingNode)
+	}
+	if plugins == nil || findKey(plugins, name) == nil {
+		return nil, fmt.Errorf("plugin %q not found in manifest", name)
+	}
+
+	foot := removeKey(plugins, name)
+	if len(plugins.Values) == 0 {
+		// An empty block mapping would render as "{}", drop the section but
+		// keep its comments in the document
+		section := findKey(root, "plugins")
+		section.FootComment = joinComments(section.GetComment(), section.Key.GetComment(), foot)
+		_ = section.SetComment(nil)
+		if orphan := removeKey(root, "plugins"); orphan != nil {
+			return []byte(orphan.String() + "\n"), nil
+		}
+	}
+
+	return render(file), nil
+}
+
+func render(file *ast.File) []byte {
+	if root, ok := file.Docs[0].Body.(*ast.MappingNode); ok && len(root.Values) == 0 {
+		return nil
+	}
+	return []byte(strings.TrimRight(file.String(), "\n") + "\n")
+}
+
+func rootMapping(file *ast.File) (*ast.MappingNode, error) {
+	if len(file.Docs) == 0 {
+		file.Docs = append(file.Docs, &ast.DocumentNode{BaseNode: &ast.BaseNode{}})
+	}
+	switch body := file.Docs[0].Body.(type) {
+	case nil:
+		node := &ast.MappingNode{BaseNode: &ast.BaseNode{}}
+		file.Docs[0].Body = node
+		return node, nil
+	case *ast.CommentGroupNode:
+		// A document of only comments, keep them above the new entries
+		node := &ast.MappingNode{BaseNode: &ast.BaseNode{Comment: body}}
+		file.Docs[0].Body = node
+		return node, nil
+	}
+
+	root, ok := file.Docs[0].Body.(*ast.MappingNode)
+	if !ok {
+		return nil, fmt.Errorf("manifest root is not a mapping")
+	}
+	return root, nil
+}
+
+func findKey(m *ast.MappingNode, key string) *ast.MappingValueNode {
+	for _, v := range m.Values {
+		if keyName(v) == key {
+			return v
+		}
+	}
+	return nil
+}
+
+// keyName returns the key of an entry without quotes or comments.
+func keyName(v *ast.MappingValueNode) string {
+	if tk := v.Key.GetToken(); tk != nil {
+		return tk.Value
+	}
+	return v.Key.String()
+}
+
+// removeKey deletes key from m. The comment lines right above the entry go
+// with it. Comments further up and below it move to the entry before it, or
+// to the one after it if it was the first; if m is left empty they are
+// returned.
+func removeKey(m *ast.MappingNode, key string) *ast.CommentGroupNode {
+	var orphan *ast.CommentGroupNode
+	values := m.Values[:0]
+	for _, v := range m.Values {
+		if keyName(v) == key {
+			kept := joinComments(detachedComments(v), v.FootComment)
+			if len(values) > 0 {
+				prev := values[len(values)-1]
+				prev.FootComment = joinComments(prev.FootComment, kept)
+			} else {
+				orphan = joinComments(orphan, kept)
+			}
+			continue
+		}
+		if orphan != nil {
+			_ = v.SetComment(joinComments(orphan, v.GetComment()))
+			orphan = nil
+		}
+		values = append(values, v)
+	}
+	m.Values = values
+	return orphan
+}
+
+// detachedComments returns the comment lines above v that a blank line
+// separates from its key. They belong to what comes before the entry.
+func detachedComments(v *ast.MappingValueNode) *ast.CommentGroupNode {
+	head := v.GetComment()
+	if head == nil {
+		return nil
+	}
+	i, line := len(head.Comments), v.Key.GetToken().Position.Line
+	for i > 0 && head.Comments[i-1].Token.Position.Line == line-1 {
+		i, line = i-1, line-1
+	}
+	return joinComments(&ast.CommentGroupNode{Comments: head.Comments[:i]})
+}
+
+// joinComments concatenates comment groups, skipping nil ones.
+func joinComments(groups ...*ast.CommentGroupNode) *ast.CommentGroupNode {
+	var comments []*token.Token
+	for _, g := range groups {
+		if g == nil {
+			continue
+		}
+		for _, c := range g.Comments {
+			comments = append(comments, c.Token)
+		}
+	}
+	if len(comments) == 0 {
+		return nil
+	}
+	return ast.CommentGroup(comments)
+}
+
+func parseNode(data []byte) (*ast.MappingNode, error) {
+	file, err := parser.ParseBytes(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	node, ok := file.Docs[0].Body.(*ast.MappingNode)
+	if !ok {
+		return nil, fmt.Errorf("unexpected node type %s", file.Docs[0].Body.Type())
+	}
+	return node, nil
+}
+
+func indent(data []byte) []byte {
+	out := []byte("  ")
+	for i, b := range data {
+		out = append(out, b)
+		if b == '\n' && i < len(data)-1 {
+			out = append(out, ' ', ' ')
+		}
+	}
+	return out
+}
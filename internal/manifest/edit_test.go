@@ -0,0 +1,282 @@
+package manifest
+
+import "testing"
+
+const editManifest = `velocity:
+  version: latest
+
+# Plugins
+plugins:
+  # note on the section
+
+  # permissions
+  luckperms:
+    version: 5.4.0
+  # after luckperms
+
+  # tab list
+  tab:
+    version: latest # pinned later
+  # after tab
+
+  # chat
+  chat:
+    version: 1.0.0
+  # after chat
+settings:
+  dest: plugins
+`
+
+func TestRemovePlugin(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		plugin string
+		want   string
+	}{
+		{
+			name:   "first",
+			data:   editManifest,
+			plugin: "luckperms",
+			want: `velocity:
+  version: latest
+
+# Plugins
+plugins:
+  # note on the section
+  # after luckperms
+
+  # tab list
+  tab:
+    version: latest # pinned later
+  # after tab
+
+  # chat
+  chat:
+    version: 1.0.0
+  # after chat
+settings:
+  dest: plugins
+`,
+		},
+		{
+			name:   "middle",
+			data:   editManifest,
+			plugin: "tab",
+			want: `velocity:
+  version: latest
+
+# Plugins
+plugins:
+  # note on the section
+
+  # permissions
+  luckperms:
+    version: 5.4.0
+  # after luckperms
+  # after tab
+
+  # chat
+  chat:
+    version: 1.0.0
+  # after chat
+settings:
+  dest: plugins
+`,
+		},
+		{
+			name:   "last",
+			data:   editManifest,
+			plugin: "chat",
+			want: `velocity:
+  version: latest
+
+# Plugins
+plugins:
+  # note on the section
+
+  # permissions
+  luckperms:
+    version: 5.4.0
+  # after luckperms
+
+  # tab list
+  tab:
+    version: latest # pinned later
+  # after tab
+  # after chat
+settings:
+  dest: plugins
+`,
+		},
+		{
+			name:   "only entry",
+			plugin: "tab",
+			data: `velocity:
+  version: latest
+
+# Plugins
+plugins: # one for now
+  # tab list
+  tab:
+    version: latest
+  # after tab
+settings:
+  dest: plugins
+`,
+			want: `velocity:
+  version: latest
+
+# Plugins
+# one for now
+# after tab
+settings:
+  dest: plugins
+`,
+		},
+		{
+			name:   "only entry of the first section",
+			plugin: "tab",
+			data: `# Plugins
+plugins:
+  tab:
+    version: latest
+  # after tab
+settings:
+  dest: plugins
+`,
+			want: `# Plugins
+# after tab
+settings:
+  dest: plugins
+`,
+		},
+		{
+			name:   "only section",
+			plugin: "tab",
+			data: `# Plugins
+plugins:
+  tab:
+    version: latest
+  # after tab
+`,
+			want: `# Plugins
+# after tab
+`,
+		},
+		{
+			name:   "quoted key without comments",
+			plugin: "tab",
+			data: `plugins:
+  "tab":
+    version: latest
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RemovePlugin([]byte(tt.data), tt.plugin)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("RemovePlugin() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemovePluginNotFound(t *testing.T) {
+	for _, data := range []string{"", "velocity:\n  version: latest\n", editManifest} {
+		if _, err := RemovePlugin([]byte(data), "missing"); err == nil {
+			t.Errorf("RemovePlugin(%q) succeeded", data)
+		}
+	}
+}
+
+func TestAddPlugin(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "empty file",
+			want: `plugins:
+  tab:
+    version: latest
+`,
+		},
+		{
+			name: "no plugins key",
+			data: `# Proxy
+velocity:
+  version: latest # track releases
+`,
+			want: `# Proxy
+velocity:
+  version: latest # track releases
+plugins:
+  tab:
+    version: latest
+`,
+		},
+		{
+			name: "only comments",
+			data: `# Plugins
+# after tab
+`,
+			want: `# Plugins
+# after tab
+plugins:
+  tab:
+    version: latest
+`,
+		},
+		{
+			name: "empty plugins key",
+			data: `plugins: # none yet
+settings:
+  dest: plugins
+`,
+			want: `plugins: # none yet
+  tab:
+    version: latest
+settings:
+  dest: plugins
+`,
+		},
+		{
+			name: "existing entries",
+			data: `plugins: # managed by scaf
+  # permissions
+  luckperms:
+    version: 5.4.0
+`,
+			want: `plugins: # managed by scaf
+  # permissions
+  luckperms:
+    version: 5.4.0
+  tab:
+    version: latest
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := AddPlugin([]byte(tt.data), "tab", &PluginConfig{Version: "latest"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("AddPlugin() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddPluginExists(t *testing.T) {
+	if _, err := AddPlugin([]byte(editManifest), "tab", &PluginConfig{Version: "latest"}); err == nil {
+		t.Error("AddPlugin() of an existing plugin succeeded")
+	}
+}
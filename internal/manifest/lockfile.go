@@ -1,40 +1,273 @@
 package manifest
 
-import "time"
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// LockfileVersion is the lock file format written by this version of scaf.
+//
+// Version 1 (no lockfile_version key) recorded resolved_at timestamps on
+// the root and on every plugin. Version 2 omits them unless requested.
+// Version 3 moves the velocity and paper keys under components. Version 4
+// adds the file of each plugin and the datapacks and resourcepacks keys,
+// which older versions would drop.
+const LockfileVersion = 4
 
 // Lockfile is the resolved plugin versions (plugins.lock.yaml).
 type Lockfile struct {
-	ResolvedAt time.Time                `yaml:"resolved_at"`
-	Velocity   *ResolvedComponent       `yaml:"velocity,omitempty"`
-	Paper      *ResolvedComponent       `yaml:"paper,omitempty"`
-	Plugins    map[string]*ResolvedPlugin `yaml:"plugins,omitempty"`
+	LockfileVersion int                           `yaml:"lockfile_version"`
+	ManifestDigest  string                        `yaml:"manifest_digest,omitempty"`
+	ResolvedAt      time.Time                     `yaml:"resolved_at,omitempty"`
+	Components      map[string]*ResolvedComponent `yaml:"components,omitempty"`
+	Plugins         map[string]*ResolvedPlugin    `yaml:"plugins,omitempty"`
+	Datapacks       map[string]*ResolvedPack      `yaml:"datapacks,omitempty"`
+	Resourcepacks   map[string]*ResolvedPack      `yaml:"resourcepacks,omitempty"`
+
+	migratedFrom int
 }
 
 // ResolvedComponent is a resolved server/proxy component.
 type ResolvedComponent struct {
-	Version string `yaml:"version"`
-	Build   int    `yaml:"build,omitempty"`
-	URL     string `yaml:"url"`
+	Source      string    `yaml:"source"`
+	Project     string    `yaml:"project"`
+	Constraint  string    `yaml:"constraint,omitempty"`
+	Version     string    `yaml:"version"`
+	Build       int       `yaml:"build,omitempty"`
+	PublishedAt time.Time `yaml:"published_at,omitempty"`
+	URL         string    `yaml:"url"`
+	MD5         string    `yaml:"md5,omitempty"`
+	SHA1        string    `yaml:"sha1,omitempty"`
+
+	// Loader is the Fabric, NeoForge or Forge version of a modded server,
+	// and Installer the Fabric installer its launcher is built with.
+	Loader    string `yaml:"loader,omitempty"`
+	Installer string `yaml:"installer,omitempty"`
+
+	// File is where download puts the jar, relative to the output
+	// directory.
+	File string `yaml:"file"`
+}
+
+// componentV2 is a server component as version 2 recorded it, under the
+// velocity and paper keys. Flavor named the paper project.
+type componentV2 struct {
+	Flavor      string    `yaml:"flavor"`
+	Constraint  string    `yaml:"constraint"`
+	Version     string    `yaml:"version"`
+	Build       int       `yaml:"build"`
+	PublishedAt time.Time `yaml:"published_at"`
+	URL         string    `yaml:"url"`
+	MD5         string    `yaml:"md5"`
+}
+
+type lockfileV2 struct {
+	Velocity *componentV2 `yaml:"velocity"`
+	Paper    *componentV2 `yaml:"paper"`
 }
 
 // ResolvedPlugin is a resolved plugin.
 type ResolvedPlugin struct {
-	Source     string    `yaml:"source"`
-	Project    string    `yaml:"project,omitempty"`
-	Version    string    `yaml:"version"`
-	Platform   string    `yaml:"platform,omitempty"`
-	Loader     string    `yaml:"loader,omitempty"`
-	URL        string    `yaml:"url,omitempty"`
-	S3URI      string    `yaml:"s3_uri,omitempty"`
-	SHA256     string    `yaml:"sha256,omitempty"`
-	SHA512     string    `yaml:"sha512,omitempty"`
-	ResolvedAt time.Time `yaml:"resolved_at"`
+	Source       string    `yaml:"source"`
+	Project      string    `yaml:"project,omitempty"`
+	Constraint   string    `yaml:"constraint,omitempty"`
+	Version      string    `yaml:"version"`
+	PublishedAt  time.Time `yaml:"published_at,omitempty"`
+	Platform     string    `yaml:"platform,omitempty"`
+	Loader       string    `yaml:"loader,omitempty"`
+	GameVersions []string  `yaml:"game_versions,omitempty"`
+	URL          string    `yaml:"url,omitempty"`
+	S3URI        string    `yaml:"s3_uri,omitempty"`
+	SHA256       string    `yaml:"sha256,omitempty"`
+	SHA512       string    `yaml:"sha512,omitempty"`
+	ResolvedAt   time.Time `yaml:"resolved_at,omitempty"`
+
+	// File is where download puts the jar, relative to the output
+	// directory. Lock files from before it was recorded use <name>.jar.
+	File string `yaml:"file,omitempty"`
+
+	// Declared is what the downloaded jar's plugin descriptor declares,
+	// recorded by 'scaf download --record'.
+	Declared *Declared `yaml:"declared,omitempty"`
+
+	// Transitive entries are not in the manifest. They were added because
+	// the entries in RequiredBy depend on them.
+	Transitive bool     `yaml:"transitive,omitempty"`
+	RequiredBy []string `yaml:"required_by,omitempty"`
+}
+
+// ResolvedPack is a resolved datapack or resource pack.
+type ResolvedPack struct {
+	Source       string    `yaml:"source"`
+	Project      string    `yaml:"project"`
+	Constraint   string    `yaml:"constraint,omitempty"`
+	Version      string    `yaml:"version"`
+	PublishedAt  time.Time `yaml:"published_at,omitempty"`
+	GameVersions []string  `yaml:"game_versions,omitempty"`
+	URL          string    `yaml:"url"`
+	// SHA1 is what server.properties expects as resource-pack-sha1.
+	SHA1   string `yaml:"sha1,omitempty"`
+	SHA512 string `yaml:"sha512,omitempty"`
+
+	// File is where download puts the pack, relative to the server
+	// directory.
+	File string `yaml:"file"`
+}
+
+// Declared is the identity a plugin jar declares in its descriptor.
+type Declared struct {
+	Platform   string   `yaml:"platform"`
+	Name       string   `yaml:"name"`
+	Version    string   `yaml:"version,omitempty"`
+	Main       string   `yaml:"main,omitempty"`
+	APIVersion string   `yaml:"api_version,omitempty"`
+	Depend     []string `yaml:"depend,omitempty"`
+	LoadBefore []string `yaml:"loadbefore,omitempty"`
+	Provides   []string `yaml:"provides,omitempty"`
 }
 
 // NewLockfile creates a new empty lockfile.
 func NewLockfile() *Lockfile {
 	return &Lockfile{
-		ResolvedAt: time.Now().UTC(),
-		Plugins:    make(map[string]*ResolvedPlugin),
+		LockfileVersion: LockfileVersion,
+		Components:      make(map[string]*ResolvedComponent),
+		Plugins:         make(map[string]*ResolvedPlugin),
+		Datapacks:       make(map[string]*ResolvedPack),
+		Resourcepacks:   make(map[string]*ResolvedPack),
+	}
+}
+
+// LoadLockfile reads and parses a lockfile.
+func LoadLockfile(path string) (*Lockfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading lock file: %w", err)
+	}
+	return ParseLockfile(data)
+}
+
+// ParseLockfile parses lock file contents, migrating older formats.
+func ParseLockfile(data []byte) (*Lockfile, error) {
+	var lf Lockfile
+	if err := yaml.Unmarshal(data, &lf); err != nil {
+		return nil, fmt.Errorf("parsing lock file: %w", err)
+	}
+	if lf.Components == nil {
+		lf.Components = make(map[string]*ResolvedComponent)
+	}
+	if lf.Plugins == nil {
+		lf.Plugins = make(map[string]*ResolvedPlugin)
+	}
+	if lf.Datapacks == nil {
+		lf.Datapacks = make(map[string]*ResolvedPack)
+	}
+	if lf.Resourcepacks == nil {
+		lf.Resourcepacks = make(map[string]*ResolvedPack)
+	}
+
+	switch {
+	case lf.LockfileVersion > LockfileVersion:
+		return nil, fmt.Errorf("lock file version %d is newer than supported (%d), upgrade scaf", lf.LockfileVersion, LockfileVersion)
+	case lf.LockfileVersion < LockfileVersion:
+		if err := lf.migrate(data); err != nil {
+			return nil, err
+		}
+	}
+	return &lf, nil
+}
+
+// migrate upgrades a lock file read in an older format to the current one.
+func (lf *Lockfile) migrate(data []byte) error {
+	lf.migratedFrom = lf.LockfileVersion
+	if lf.migratedFrom == 0 {
+		lf.migratedFrom = 1
+	}
+
+	// Version 1 -> 2: drop wall-clock timestamps
+	if lf.migratedFrom < 2 {
+		lf.ResolvedAt = time.Time{}
+		for _, p := range lf.Plugins {
+			p.ResolvedAt = time.Time{}
+		}
+	}
+
+	// Version 2 -> 3: move velocity and paper under components
+	if lf.migratedFrom < 3 {
+		if err := lf.migrateComponents(data); err != nil {
+			return err
+		}
+	}
+
+	// Version 3 -> 4: only adds keys, plugins without a file keep <name>.jar
+	lf.LockfileVersion = LockfileVersion
+	return nil
+}
+
+// migrateComponents moves the velocity and paper keys of a version 2 lock
+// file under components.
+func (lf *Lockfile) migrateComponents(data []byte) error {
+	var old lockfileV2
+	if err := yaml.Unmarshal(data, &old); err != nil {
+		return fmt.Errorf("parsing lock file: %w", err)
+	}
+	for name, c := range map[string]*componentV2{"velocity": old.Velocity, "paper": old.Paper} {
+		if c == nil {
+			continue
+		}
+		project := c.Flavor
+		if project == "" {
+			project = name
+		}
+		lf.Components[name] = &ResolvedComponent{
+			Source:      componentSource(project),
+			Project:     project,
+			Constraint:  c.Constraint,
+			Version:     c.Version,
+			Build:       c.Build,
+			PublishedAt: c.PublishedAt,
+			URL:         c.URL,
+			MD5:         c.MD5,
+			File:        project + ".jar",
+		}
+	}
+	return nil
+}
+
+// Migrated reports whether the lock file was read in an older format and
+// will be rewritten in the current one.
+func (lf *Lockfile) Migrated() bool {
+	return lf.migratedFrom != 0
+}
+
+// Timestamped reports whether the lock file records resolution times.
+func (lf *Lockfile) Timestamped() bool {
+	return !lf.ResolvedAt.IsZero()
+}
+
+// Marshal renders the lockfile as YAML, including the generated header.
+func (lf *Lockfile) Marshal() ([]byte, error) {
+	output, err := yaml.Marshal(lf)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling lockfile: %w", err)
+	}
+
+	content := "# Auto-generated lock file - do not edit manually\n"
+	content += "# Generated by scaf\n\n"
+	content += string(output)
+	return []byte(content), nil
+}
+
+// Write marshals the lockfile and writes it to path.
+func (lf *Lockfile) Write(path string) error {
+	content, err := lf.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("writing lock file: %w", err)
 	}
+	return nil
 }
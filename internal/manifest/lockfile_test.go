@@ -0,0 +1,144 @@
+package manifest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLockfileMigrates(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		components map[string]*ResolvedComponent
+	}{
+		{
+			name: "version 1",
+			data: `resolved_at: 2024-05-01T10:00:00Z
+velocity:
+  constraint: latest
+  version: 3.3.0-SNAPSHOT
+  build: 400
+  url: https://api.papermc.io/v2/projects/velocity/versions/3.3.0-SNAPSHOT/builds/400/downloads/velocity.jar
+plugins:
+  luckperms:
+    source: modrinth
+    project: luckperms
+    version: 5.4.0
+    url: https://cdn.example/luckperms.jar
+    resolved_at: 2024-05-01T10:00:00Z
+`,
+			components: map[string]*ResolvedComponent{
+				"velocity": {
+					Source:     "papermc",
+					Project:    "velocity",
+					Constraint: "latest",
+					Version:    "3.3.0-SNAPSHOT",
+					Build:      400,
+					URL:        "https://api.papermc.io/v2/projects/velocity/versions/3.3.0-SNAPSHOT/builds/400/downloads/velocity.jar",
+					File:       "velocity.jar",
+				},
+			},
+		},
+		{
+			name: "version 2",
+			data: `lockfile_version: 2
+paper:
+  flavor: purpur
+  constraint: 1.21.4
+  version: 1.21.4
+  build: 2416
+  url: https://api.purpurmc.org/v2/purpur/1.21.4/2416/download
+  md5: 1e2d3c4b5a6f7e8d9c0b1a2f3e4d5c6b
+plugins:
+  luckperms:
+    source: modrinth
+    project: luckperms
+    version: 5.4.0
+    url: https://cdn.example/luckperms.jar
+`,
+			components: map[string]*ResolvedComponent{
+				"paper": {
+					Source:     "purpur",
+					Project:    "purpur",
+					Constraint: "1.21.4",
+					Version:    "1.21.4",
+					Build:      2416,
+					URL:        "https://api.purpurmc.org/v2/purpur/1.21.4/2416/download",
+					MD5:        "1e2d3c4b5a6f7e8d9c0b1a2f3e4d5c6b",
+					File:       "purpur.jar",
+				},
+			},
+		},
+		{
+			name: "version 3",
+			data: `lockfile_version: 3
+components:
+  paper:
+    source: papermc
+    project: paper
+    version: 1.21.4
+    build: 100
+    url: https://cdn.example/paper.jar
+    file: paper.jar
+plugins:
+  luckperms:
+    source: modrinth
+    project: luckperms
+    version: 5.4.0
+    url: https://cdn.example/luckperms.jar
+`,
+			components: map[string]*ResolvedComponent{
+				"paper": {
+					Source:  "papermc",
+					Project: "paper",
+					Version: "1.21.4",
+					Build:   100,
+					URL:     "https://cdn.example/paper.jar",
+					File:    "paper.jar",
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lf, err := ParseLockfile([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lf.LockfileVersion != LockfileVersion || !lf.Migrated() {
+				t.Errorf("version = %d, migrated = %v, want %d, true", lf.LockfileVersion, lf.Migrated(), LockfileVersion)
+			}
+			if lf.Timestamped() || !lf.Plugins["luckperms"].ResolvedAt.IsZero() {
+				t.Error("timestamps were kept")
+			}
+			if !reflect.DeepEqual(lf.Components, tt.components) {
+				t.Errorf("components = %+v, want %+v", lf.Components, tt.components)
+			}
+			if p := lf.Plugins["luckperms"]; p == nil || p.Version != "5.4.0" || p.File != "" {
+				t.Errorf("luckperms = %+v, want version 5.4.0 without file", p)
+			}
+
+			// The rewritten lock file reads back in the current format
+			data, err := lf.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := ParseLockfile(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.Migrated() || !reflect.DeepEqual(again.Components, tt.components) {
+				t.Errorf("rewritten lock file = %+v, migrated = %v", again.Components, again.Migrated())
+			}
+		})
+	}
+}
+
+func TestParseLockfileNewer(t *testing.T) {
+	_, err := ParseLockfile([]byte("lockfile_version: 99\n"))
+	if err == nil || !strings.Contains(err.Error(), "upgrade scaf") {
+		t.Errorf("ParseLockfile() error = %v, want an upgrade hint", err)
+	}
+}
@@ -1,21 +1,183 @@
 // Package manifest defines types for plugin manifests and lockfiles.
 package manifest
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
 // Manifest is the input configuration file (plugins.yaml).
 type Manifest struct {
-	Velocity VelocityConfig           `yaml:"velocity,omitempty"`
-	Paper    PaperConfig              `yaml:"paper,omitempty"`
-	Plugins  map[string]*PluginConfig `yaml:"plugins,omitempty"`
+	Settings   Settings                    `yaml:"settings,omitempty"`
+	Components map[string]*ComponentConfig `yaml:"components,omitempty"`
+
+	// Velocity and Paper are shorthands for the components of the same
+	// name, see AllComponents.
+	Velocity VelocityConfig `yaml:"velocity,omitempty"`
+	Paper    PaperConfig    `yaml:"paper,omitempty"`
+
+	Plugins map[string]*PluginConfig `yaml:"plugins,omitempty"`
+
+	// Datapacks and Resourcepacks are resolved from Modrinth.
+	Datapacks     map[string]*PackConfig `yaml:"datapacks,omitempty"`
+	Resourcepacks map[string]*PackConfig `yaml:"resourcepacks,omitempty"`
+}
+
+// Settings are manifest-wide defaults. Entries can override them.
+type Settings struct {
+	Policy `yaml:",inline"`
+
+	// InferGameVersions resolves Paper plugins without game_versions of
+	// their own for the resolved Paper version.
+	InferGameVersions bool `yaml:"infer_game_versions,omitempty"`
+
+	// Dest is the directory plugins are downloaded to, relative to the
+	// output directory, and Filename the template for their file names
+	// (DefaultFilename if unset).
+	Dest     string `yaml:"dest,omitempty"`
+	Filename string `yaml:"filename,omitempty"`
+
+	// World is the world folder datapacks are downloaded to, relative to
+	// the server directory ("world" if unset).
+	World string `yaml:"world,omitempty"`
+}
+
+// DatapackDest returns the directory datapacks go to by default.
+func (s Settings) DatapackDest() string {
+	world := s.World
+	if world == "" {
+		world = "world"
+	}
+	return path.Join(world, "datapacks")
+}
+
+// ResourcepackDest is the directory resource packs go to by default.
+const ResourcepackDest = "resourcepacks"
+
+// Policy limits which versions resolve and update may select. It can be set
+// in settings and on each entry; entry values take precedence.
+type Policy struct {
+	// MaxBump limits how far a locked version may move: "major" (no limit,
+	// the default), "minor" or "patch".
+	MaxBump string `yaml:"max_bump,omitempty"`
+	// MinAge skips versions published more recently, e.g. "3d" or "12h".
+	MinAge string `yaml:"min_age,omitempty"`
+}
+
+// Merge returns p with unset values taken from defaults.
+func (p Policy) Merge(defaults Policy) Policy {
+	if p.MaxBump == "" {
+		p.MaxBump = defaults.MaxBump
+	}
+	if p.MinAge == "" {
+		p.MinAge = defaults.MinAge
+	}
+	return p
 }
 
 // VelocityConfig configures the Velocity proxy.
 type VelocityConfig struct {
 	Version string `yaml:"version,omitempty"`
+	Policy  `yaml:",inline"`
 }
 
 // PaperConfig configures Paper server.
 type PaperConfig struct {
 	Version string `yaml:"version,omitempty"`
+
+	// Flavor selects the server implementation: paper (the default),
+	// folia or purpur.
+	Flavor string `yaml:"flavor,omitempty"`
+	Policy `yaml:",inline"`
+}
+
+// ServerFlavors lists the supported values of paper.flavor.
+var ServerFlavors = []string{"paper", "folia", "purpur"}
+
+// ComponentConfig configures a server or proxy component.
+type ComponentConfig struct {
+	// Source is the resolver to use. It defaults to purpur, fabric,
+	// neoforge and forge for those projects and to papermc otherwise.
+	Source string `yaml:"source,omitempty"`
+	// Project defaults to the component name.
+	Project string `yaml:"project,omitempty"`
+	Version string `yaml:"version,omitempty"`
+	// Loader constrains the Fabric, NeoForge or Forge version of a modded
+	// server and Installer the Fabric installer version, the newest stable
+	// ones by default.
+	Loader    string `yaml:"loader,omitempty"`
+	Installer string `yaml:"installer,omitempty"`
+	// File is the name of the downloaded jar, <project>.jar by default, or
+	// <project>-installer.jar for NeoForge and Forge. It goes into Dest,
+	// relative to the output directory.
+	File   string `yaml:"file,omitempty"`
+	Dest   string `yaml:"dest,omitempty"`
+	Policy `yaml:",inline"`
+}
+
+// Path returns where the component's jar goes, relative to the output
+// directory.
+func (c *ComponentConfig) Path() string {
+	return path.Join(c.Dest, c.File)
+}
+
+// withDefaults returns a copy of c with unset fields filled in.
+func (c ComponentConfig) withDefaults(name string) *ComponentConfig {
+	if c.Project == "" {
+		c.Project = name
+	}
+	if c.Source == "" {
+		c.Source = componentSource(c.Project)
+	}
+	if c.File == "" {
+		c.File = c.Project + ".jar"
+		if c.Source == "neoforge" || c.Source == "forge" {
+			c.File = c.Project + "-installer.jar"
+		}
+	}
+	return &c
+}
+
+func componentSource(project string) string {
+	switch project {
+	case "purpur", "fabric", "neoforge", "forge":
+		return project
+	}
+	return "papermc"
+}
+
+// AllComponents returns the configured components by name, with defaults
+// filled in. The velocity and paper keys are mapped onto components of the
+// same name; paper.flavor selects the project.
+func (m *Manifest) AllComponents() map[string]*ComponentConfig {
+	components := make(map[string]*ComponentConfig, len(m.Components)+2)
+	for name, c := range m.Components {
+		components[name] = c.withDefaults(name)
+	}
+	if m.Velocity.Version != "" {
+		components["velocity"] = ComponentConfig{
+			Version: m.Velocity.Version,
+			Policy:  m.Velocity.Policy,
+		}.withDefaults("velocity")
+	}
+	if m.Paper.Version != "" {
+		components["paper"] = ComponentConfig{
+			Project: m.Paper.Flavor,
+			Version: m.Paper.Version,
+			Policy:  m.Paper.Policy,
+		}.withDefaults("paper")
+	}
+	return components
 }
 
 // PluginConfig is the configuration for a single plugin.
@@ -29,6 +191,21 @@ type PluginConfig struct {
 	Bucket       string   `yaml:"bucket,omitempty"`
 	Key          string   `yaml:"key,omitempty"`
 	URL          string   `yaml:"url,omitempty"`
+	// Dest overrides settings.dest for this entry.
+	Dest   string `yaml:"dest,omitempty"`
+	Policy `yaml:",inline"`
+}
+
+// PackConfig configures a datapack or resource pack.
+type PackConfig struct {
+	// Project is the Modrinth project, the entry name by default.
+	Project      string   `yaml:"project,omitempty"`
+	Version      string   `yaml:"version,omitempty"`
+	GameVersions []string `yaml:"game_versions,omitempty"`
+	// Dest overrides the world's datapacks folder or resourcepacks,
+	// relative to the server directory.
+	Dest   string `yaml:"dest,omitempty"`
+	Policy `yaml:",inline"`
 }
 
 // ToResolverConfig converts to resolver.PluginConfig.
@@ -43,5 +220,146 @@ func (p *PluginConfig) ToResolverConfig() map[string]interface{} {
 		"bucket":        p.Bucket,
 		"key":           p.Key,
 		"url":           p.URL,
+		"dest":          p.Dest,
+		"max_bump":      p.MaxBump,
+		"min_age":       p.MinAge,
+	}
+}
+
+// Load reads and parses a manifest file.
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing manifest: %w", err)
+	}
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return &m, nil
+}
+
+func (m *Manifest) validate() error {
+	if m.Paper.Flavor != "" && !slices.Contains(ServerFlavors, m.Paper.Flavor) {
+		return fmt.Errorf("paper.flavor must be one of %s, got %q", strings.Join(ServerFlavors, ", "), m.Paper.Flavor)
+	}
+	policies := map[string]Policy{
+		"settings": m.Settings.Policy,
+		"velocity": m.Velocity.Policy,
+		"paper":    m.Paper.Policy,
+	}
+	legacy := map[string]string{"velocity": m.Velocity.Version, "paper": m.Paper.Version}
+	for name, c := range m.Components {
+		switch {
+		case name == "":
+			return fmt.Errorf("components: entry with an empty name")
+		case c == nil:
+			return fmt.Errorf("components.%s: empty entry", name)
+		case c.Version == "":
+			return fmt.Errorf("components.%s: version is required", name)
+		case legacy[name] != "":
+			return fmt.Errorf("components.%s: also configured under %s", name, name)
+		case m.Plugins[name] != nil:
+			return fmt.Errorf("components.%s: a plugin has the same name", name)
+		}
+		if c.File != "" && (c.File != filepath.Base(c.File) || c.File == "." || c.File == "..") {
+			return fmt.Errorf("components.%s: file must be a file name, got %q", name, c.File)
+		}
+		if err := validateDest(c.Dest); err != nil {
+			return fmt.Errorf("components.%s: dest: %w", name, err)
+		}
+		policies["components."+name] = c.Policy
+	}
+	if err := validateDest(m.Settings.Dest); err != nil {
+		return fmt.Errorf("settings: dest: %w", err)
+	}
+	if err := validateFilename(m.Settings.Filename); err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	for name, p := range m.Plugins {
+		if p == nil {
+			return fmt.Errorf("plugins.%s: empty entry", name)
+		}
+		if err := validateDest(p.Dest); err != nil {
+			return fmt.Errorf("plugins.%s: dest: %w", name, err)
+		}
+		policies["plugins."+name] = p.Policy
+	}
+	if err := validateDest(m.Settings.World); err != nil {
+		return fmt.Errorf("settings: world: %w", err)
+	}
+	components := m.AllComponents()
+	for section, packs := range map[string]map[string]*PackConfig{
+		"datapacks":     m.Datapacks,
+		"resourcepacks": m.Resourcepacks,
+	} {
+		for name, p := range packs {
+			// Names select entries in commands such as update
+			switch {
+			case p == nil:
+				return fmt.Errorf("%s.%s: empty entry", section, name)
+			case m.Plugins[name] != nil || components[name] != nil:
+				return fmt.Errorf("%s.%s: a plugin or component has the same name", section, name)
+			case section == "resourcepacks" && m.Datapacks[name] != nil:
+				return fmt.Errorf("%s.%s: a datapack has the same name", section, name)
+			}
+			if err := validateDest(p.Dest); err != nil {
+				return fmt.Errorf("%s.%s: dest: %w", section, name, err)
+			}
+			policies[section+"."+name] = p.Policy
+		}
+	}
+	for name, p := range policies {
+		if err := p.validate(); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (p Policy) validate() error {
+	switch p.MaxBump {
+	case "", "major", "minor", "patch":
+	default:
+		return fmt.Errorf("max_bump must be major, minor or patch, got %q", p.MaxBump)
+	}
+	if _, err := ParseAge(p.MinAge); err != nil {
+		return fmt.Errorf("min_age: %w", err)
+	}
+	return nil
+}
+
+// ParseAge parses a min_age value: a whole number of days such as "3d", or
+// a Go duration such as "36h". The empty string is zero.
+func ParseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// Digest returns a hash of the manifest's content. Comments, formatting and
+// key order do not affect it, so it only changes when the manifest does.
+func (m *Manifest) Digest() (string, error) {
+	data, err := yaml.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("marshaling manifest: %w", err)
 	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:]), nil
 }
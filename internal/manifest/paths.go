@@ -0,0 +1,62 @@
+package manifest
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultFilename is the filename template used unless settings set one.
+const DefaultFilename = "{name}.jar"
+
+// JarFile returns where the jar of an entry goes, relative to the output
+// directory: dest joined with the filename template, in which {name},
+// {version} and {original} (the upstream file name) are replaced. The
+// result uses forward slashes and never leaves the output directory.
+func JarFile(dest, template, name, version, original string) (string, error) {
+	if template == "" {
+		template = DefaultFilename
+	}
+	// Values come from upstream and must not add directories
+	clean := strings.NewReplacer("/", "_", `\`, "_")
+	file := strings.NewReplacer(
+		"{name}", clean.Replace(name),
+		"{version}", clean.Replace(version),
+		"{original}", clean.Replace(original),
+	).Replace(template)
+
+	p := path.Join(dest, file)
+	if err := checkLocal(p); err != nil {
+		return "", err
+	}
+	return p, nil
+}
+
+// checkLocal rejects paths that are absolute or leave the directory they
+// are relative to.
+func checkLocal(p string) error {
+	if !filepath.IsLocal(filepath.FromSlash(p)) {
+		return fmt.Errorf("%q is outside the download directory", p)
+	}
+	return nil
+}
+
+func validateFilename(template string) error {
+	if template == "" {
+		return nil
+	}
+	rest := strings.NewReplacer("{name}", "", "{version}", "", "{original}", "").Replace(template)
+	if strings.ContainsAny(rest, "{}") {
+		return fmt.Errorf("filename %q: only {name}, {version} and {original} can be used", template)
+	}
+	_, err := JarFile("", template, "name", "1.0.0", "name-1.0.0.jar")
+	return err
+}
+
+func validateDest(dest string) error {
+	if dest == "" {
+		return nil
+	}
+	return checkLocal(dest)
+}
@@ -0,0 +1,132 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const fabricMetaAPIBase = "https://meta.fabricmc.net/v2"
+
+// FabricResolver resolves Fabric server launchers from Fabric Meta. The
+// version constraint selects the Minecraft version; LoaderVersion and
+// InstallerVersion select the loader and installer, the newest stable ones
+// by default.
+type FabricResolver struct {
+	client *http.Client
+}
+
+// NewFabricResolver creates a new Fabric resolver.
+func NewFabricResolver(client *http.Client) *FabricResolver {
+	return &FabricResolver{client: client}
+}
+
+func (f *FabricResolver) Name() string { return "fabric" }
+
+func (f *FabricResolver) Resolve(ctx context.Context, cfg PluginConfig) (*Result, error) {
+	var games []fabricVersion
+	if err := f.get(ctx, fabricMetaAPIBase+"/versions/game", &games); err != nil {
+		return nil, fmt.Errorf("fetching game versions: %w", err)
+	}
+	game, heldBack, err := selectVersion(fabricVersions(games, cfg.Version), cfg)
+	if err != nil {
+		return nil, fmt.Errorf("selecting game version: %w", err)
+	}
+	if game == "" {
+		return nil, fmt.Errorf("no Minecraft version supported by Fabric matches constraint %q", cfg.Version)
+	}
+
+	// Only loaders that support the game version are listed for it
+	var loaders []struct {
+		Loader fabricVersion `json:"loader"`
+	}
+	if err := f.get(ctx, fmt.Sprintf("%s/versions/loader/%s", fabricMetaAPIBase, game), &loaders); err != nil {
+		return nil, fmt.Errorf("fetching loader versions: %w", err)
+	}
+	loaderVersions := make([]fabricVersion, len(loaders))
+	for i, l := range loaders {
+		loaderVersions[i] = l.Loader
+	}
+	loader, err := SelectBestVersion(fabricVersions(loaderVersions, cfg.LoaderVersion), cfg.LoaderVersion)
+	if err != nil {
+		return nil, fmt.Errorf("selecting loader version: %w", err)
+	}
+	if loader == "" {
+		return nil, fmt.Errorf("no Fabric loader for %s matches constraint %q", game, cfg.LoaderVersion)
+	}
+
+	var installers []fabricVersion
+	if err := f.get(ctx, fabricMetaAPIBase+"/versions/installer", &installers); err != nil {
+		return nil, fmt.Errorf("fetching installer versions: %w", err)
+	}
+	installer, err := SelectBestVersion(fabricVersions(installers, cfg.InstallerVersion), cfg.InstallerVersion)
+	if err != nil {
+		return nil, fmt.Errorf("selecting installer version: %w", err)
+	}
+	if installer == "" {
+		return nil, fmt.Errorf("no Fabric installer matches constraint %q", cfg.InstallerVersion)
+	}
+
+	return &Result{
+		Source:           "fabric",
+		Project:          "fabric",
+		Version:          game,
+		LoaderVersion:    loader,
+		InstallerVersion: installer,
+		URL: fmt.Sprintf("%s/versions/loader/%s/%s/%s/server/jar",
+			fabricMetaAPIBase, game, loader, installer),
+		ResolvedAt: time.Now().UTC(),
+		HeldBack:   heldBack,
+	}, nil
+}
+
+// Versions returns the stable Minecraft versions Fabric supports.
+func (f *FabricResolver) Versions(ctx context.Context, cfg PluginConfig) ([]string, error) {
+	var games []fabricVersion
+	if err := f.get(ctx, fabricMetaAPIBase+"/versions/game", &games); err != nil {
+		return nil, err
+	}
+	return fabricVersions(games, ""), nil
+}
+
+type fabricVersion struct {
+	Version string `json:"version"`
+	Stable  bool   `json:"stable"`
+}
+
+// fabricVersions returns the stable versions, newest first as Fabric Meta
+// lists them, plus the constraint itself if it pins an unstable one.
+func fabricVersions(versions []fabricVersion, constraint string) []string {
+	var stable []string
+	for _, v := range versions {
+		if v.Stable || v.Version == constraint {
+			stable = append(stable, v.Version)
+		}
+	}
+	return stable
+}
+
+func (f *FabricResolver) get(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Fabric Meta returned %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
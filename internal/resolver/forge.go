@@ -0,0 +1,221 @@
+package resolver
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ForgeResolver resolves NeoForge and Forge server installers from their
+// Maven repositories. As with Fabric, the version constraint selects the
+// Minecraft version and LoaderVersion the loader, the newest stable one by
+// default.
+type ForgeResolver struct {
+	client   *http.Client
+	name     string
+	repo     string // Maven directory of the artifact
+	artifact string
+
+	// split maps a Maven version to its Minecraft and loader version, and
+	// join does the reverse.
+	split func(version string) (game, loader string, ok bool)
+	join  func(game, loader string) string
+}
+
+// NewNeoForgeResolver creates a resolver for NeoForge, whose versions
+// encode the Minecraft version: 21.1.77 is for Minecraft 1.21.1.
+func NewNeoForgeResolver(client *http.Client) *ForgeResolver {
+	return &ForgeResolver{
+		client:   client,
+		name:     "neoforge",
+		repo:     "https://maven.neoforged.net/releases/net/neoforged/neoforge",
+		artifact: "neoforge",
+		split: func(version string) (string, string, bool) {
+			parts := strings.SplitN(version, ".", 3)
+			if len(parts) < 3 {
+				return "", "", false
+			}
+			game := "1." + parts[0]
+			if parts[1] != "0" {
+				game += "." + parts[1]
+			}
+			return game, version, true
+		},
+		join: func(game, loader string) string { return loader },
+	}
+}
+
+// NewForgeResolver creates a resolver for Minecraft Forge, whose versions
+// are the Minecraft and Forge version joined by a dash: 1.20.1-47.2.0.
+func NewForgeResolver(client *http.Client) *ForgeResolver {
+	return &ForgeResolver{
+		client:   client,
+		name:     "forge",
+		repo:     "https://maven.minecraftforge.net/net/minecraftforge/forge",
+		artifact: "forge",
+		split: func(version string) (string, string, bool) {
+			game, loader, ok := strings.Cut(version, "-")
+			return game, loader, ok && loader != ""
+		},
+		join: func(game, loader string) string { return game + "-" + loader },
+	}
+}
+
+func (f *ForgeResolver) Name() string { return f.name }
+
+func (f *ForgeResolver) Resolve(ctx context.Context, cfg PluginConfig) (*Result, error) {
+	loaders, err := f.fetchVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching versions: %w", err)
+	}
+
+	games := newestFirst(slices.Collect(maps.Keys(loaders)))
+	game, heldBack, err := selectVersion(games, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("selecting game version: %w", err)
+	}
+	if game == "" {
+		return nil, fmt.Errorf("no Minecraft version supported by %s matches constraint %q", f.name, cfg.Version)
+	}
+
+	loader, err := SelectBestVersion(stableLoaders(loaders[game], cfg.LoaderVersion), cfg.LoaderVersion)
+	if err != nil {
+		return nil, fmt.Errorf("selecting loader version: %w", err)
+	}
+	if loader == "" {
+		return nil, fmt.Errorf("no %s version for %s matches constraint %q", f.name, game, cfg.LoaderVersion)
+	}
+
+	version := f.join(game, loader)
+	url := fmt.Sprintf("%s/%s/%s-%s-installer.jar", f.repo, version, f.artifact, version)
+	sha1, err := f.fetchChecksum(ctx, url+".sha1")
+	if err != nil {
+		return nil, fmt.Errorf("fetching installer checksum: %w", err)
+	}
+
+	return &Result{
+		Source:        f.name,
+		Project:       f.name,
+		Version:       game,
+		LoaderVersion: loader,
+		URL:           url,
+		SHA1:          sha1,
+		ResolvedAt:    time.Now().UTC(),
+		HeldBack:      heldBack,
+	}, nil
+}
+
+// Versions returns the Minecraft versions the loader has releases for.
+func (f *ForgeResolver) Versions(ctx context.Context, cfg PluginConfig) ([]string, error) {
+	loaders, err := f.fetchVersions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return newestFirst(slices.Collect(maps.Keys(loaders))), nil
+}
+
+type mavenMetadata struct {
+	Versions []string `xml:"versioning>versions>version"`
+}
+
+// fetchVersions returns the loader versions for each Minecraft version,
+// newest first.
+func (f *ForgeResolver) fetchVersions(ctx context.Context) (map[string][]string, error) {
+	body, err := f.get(ctx, f.repo+"/maven-metadata.xml")
+	if err != nil {
+		return nil, err
+	}
+	var data mavenMetadata
+	if err := xml.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("parsing maven metadata: %w", err)
+	}
+
+	loaders := make(map[string][]string)
+	for _, v := range data.Versions {
+		if game, loader, ok := f.split(v); ok {
+			loaders[game] = append(loaders[game], loader)
+		}
+	}
+	for game, versions := range loaders {
+		loaders[game] = newestFirst(versions)
+	}
+	return loaders, nil
+}
+
+// fetchChecksum reads a Maven checksum file, which holds the hex digest
+// optionally followed by the file name.
+func (f *ForgeResolver) fetchChecksum(ctx context.Context, url string) (string, error) {
+	body, err := f.get(ctx, url)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Fields(string(body))
+	if len(fields) == 0 {
+		return "", fmt.Errorf("empty checksum file")
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
+func (f *ForgeResolver) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s Maven returned %d", f.name, resp.StatusCode)
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// stableLoaders drops prereleases such as NeoForge betas unless the
+// constraint asks for specific versions or there is no stable release yet.
+func stableLoaders(versions []string, constraint string) []string {
+	if constraint != "" && constraint != "latest" {
+		return versions
+	}
+	var stable []string
+	for _, v := range versions {
+		if sv, err := ParseVersion(v); err == nil && sv.Prerelease() == "" {
+			stable = append(stable, v)
+		}
+	}
+	if len(stable) == 0 {
+		return versions
+	}
+	return stable
+}
+
+// newestFirst sorts versions newest first. Versions that cannot be parsed
+// go last.
+func newestFirst(versions []string) []string {
+	sort.SliceStable(versions, func(i, j int) bool {
+		a, errA := ParseVersion(versions[i])
+		b, errB := ParseVersion(versions[j])
+		switch {
+		case errA != nil:
+			return false
+		case errB != nil:
+			return true
+		}
+		return a.GreaterThan(b)
+	})
+	return versions
+}
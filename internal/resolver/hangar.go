@@ -5,6 +5,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -23,6 +26,77 @@ func NewHangarResolver(client *http.Client) *HangarResolver {
 func (h *HangarResolver) Name() string { return "hangar" }
 
 func (h *HangarResolver) Resolve(ctx context.Context, cfg PluginConfig) (*Result, error) {
+	available, platform, err := h.available(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Extract version strings, skipping versions younger than MinAge
+	var versionStrings []string
+	for _, v := range available {
+		if !tooNew(v.CreatedAt, cfg.MinAge) {
+			versionStrings = append(versionStrings, v.Name)
+		}
+	}
+	if len(versionStrings) == 0 {
+		return nil, fmt.Errorf("no version of %s is older than %s", cfg.Project, cfg.MinAge)
+	}
+
+	// Select best version based on constraint
+	selectedVersion, heldBack, err := selectVersion(versionStrings, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("selecting version: %w", err)
+	}
+	if selectedVersion == "" {
+		return nil, fmt.Errorf("no version of %s matches constraint %q", cfg.Project, cfg.Version)
+	}
+
+	// Find the selected version data
+	var selected *hangarVersion
+	for _, v := range available {
+		if v.Name == selectedVersion {
+			selected = &v
+			break
+		}
+	}
+
+	download := selected.Downloads[platform]
+	deps, err := h.dependencies(ctx, selected.PluginDependencies[platform])
+	if err != nil {
+		return nil, fmt.Errorf("fetching dependencies: %w", err)
+	}
+
+	return &Result{
+		Source:       "hangar",
+		Project:      cfg.Project,
+		Version:      selected.Name,
+		Platform:     platform,
+		URL:          download.DownloadURL,
+		SHA256:       download.FileInfo.SHA256Hash,
+		ResolvedAt:   time.Now().UTC(),
+		PublishedAt:  selected.CreatedAt.UTC(),
+		Dependencies: deps,
+		HeldBack:     heldBack,
+	}, nil
+}
+
+// Versions returns the versions available for the configured platform.
+func (h *HangarResolver) Versions(ctx context.Context, cfg PluginConfig) ([]string, error) {
+	available, _, err := h.available(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, len(available))
+	for i, v := range available {
+		versions[i] = v.Name
+	}
+	return versions, nil
+}
+
+// available fetches the versions that have downloads for the configured
+// platform, newest first.
+func (h *HangarResolver) available(ctx context.Context, cfg PluginConfig) ([]hangarVersion, string, error) {
 	platform := cfg.Platform
 	if platform == "" {
 		platform = "VELOCITY"
@@ -31,56 +105,120 @@ func (h *HangarResolver) Resolve(ctx context.Context, cfg PluginConfig) (*Result
 	// Fetch all versions
 	versions, err := h.fetchVersions(ctx, cfg.Project)
 	if err != nil {
-		return nil, fmt.Errorf("fetching versions: %w", err)
+		return nil, "", fmt.Errorf("fetching versions: %w", err)
 	}
 
-	// Filter versions that have downloads for our platform
+	// Filter versions that have downloads for our platform and, if
+	// requested, support one of the game versions
 	var available []hangarVersion
 	for _, v := range versions {
-		if _, ok := v.Downloads[platform]; ok {
-			available = append(available, v)
+		if _, ok := v.Downloads[platform]; !ok {
+			continue
 		}
+		if len(cfg.GameVersions) > 0 && !slices.ContainsFunc(v.PlatformDependencies[platform], func(gv string) bool {
+			return slices.Contains(cfg.GameVersions, gv)
+		}) {
+			continue
+		}
+		available = append(available, v)
 	}
 
 	if len(available) == 0 {
-		return nil, fmt.Errorf("no versions found for %s on %s", cfg.Project, platform)
+		if len(cfg.GameVersions) > 0 {
+			return nil, "", fmt.Errorf("no versions found for %s on %s %s", cfg.Project, platform, strings.Join(cfg.GameVersions, ", "))
+		}
+		return nil, "", fmt.Errorf("no versions found for %s on %s", cfg.Project, platform)
+	}
+
+	return available, platform, nil
+}
+
+// Notes returns the descriptions of the versions between from and to.
+func (h *HangarResolver) Notes(ctx context.Context, cfg PluginConfig, from, to *Result) ([]ReleaseNote, error) {
+	available, _, err := h.available(ctx, cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	// Extract version strings
-	versionStrings := make([]string, len(available))
+	versions := make([]string, len(available))
 	for i, v := range available {
-		versionStrings[i] = v.Name
+		versions[i] = v.Name
 	}
 
-	// Select best version based on constraint
-	selectedVersion, err := SelectBestVersion(versionStrings, cfg.Version)
+	var notes []ReleaseNote
+	for _, i := range versionsBetween(versions, fromVersion(from), to.Version) {
+		notes = append(notes, ReleaseNote{Version: available[i].Name, Text: available[i].Description})
+	}
+	return notes, nil
+}
+
+// Identify searches Hangar for a project with the jar's declared name and
+// matches the jar against that project's versions by SHA-256 hash.
+func (h *HangarResolver) Identify(ctx context.Context, file LocalFile) (*Result, error) {
+	if file.Name == "" || file.SHA256 == "" {
+		return nil, nil
+	}
+
+	projects, err := h.searchProjects(ctx, file.Name)
 	if err != nil {
-		return nil, fmt.Errorf("selecting version: %w", err)
+		return nil, err
 	}
-	if selectedVersion == "" {
-		return nil, fmt.Errorf("no version of %s matches constraint %q", cfg.Project, cfg.Version)
+
+	platform := strings.ToUpper(file.Platform)
+	for _, p := range projects {
+		project := p.Namespace.Owner + "/" + p.Namespace.Slug
+		versions, err := h.fetchVersions(ctx, project)
+		if err != nil {
+			return nil, fmt.Errorf("fetching versions of %s: %w", project, err)
+		}
+
+		for _, v := range versions {
+			download, ok := v.Downloads[platform]
+			if !ok || !strings.EqualFold(download.FileInfo.SHA256Hash, file.SHA256) {
+				continue
+			}
+			return &Result{
+				Source:     "hangar",
+				Project:    project,
+				Version:    v.Name,
+				Platform:   platform,
+				URL:        download.DownloadURL,
+				SHA256:     download.FileInfo.SHA256Hash,
+				ResolvedAt: time.Now().UTC(),
+			}, nil
+		}
 	}
 
-	// Find the selected version data
-	var selected *hangarVersion
-	for _, v := range available {
-		if v.Name == selectedVersion {
-			selected = &v
-			break
+	return nil, nil
+}
+
+// searchProjects returns the projects whose name or slug is name.
+func (h *HangarResolver) searchProjects(ctx context.Context, name string) ([]hangarProject, error) {
+	var search hangarProjectsResponse
+	apiURL := fmt.Sprintf("%s/projects?q=%s&limit=25", hangarAPIBase, url.QueryEscape(name))
+	if err := h.get(ctx, apiURL, &search); err != nil {
+		return nil, fmt.Errorf("searching projects: %w", err)
+	}
+
+	var matches []hangarProject
+	for _, p := range search.Result {
+		if strings.EqualFold(p.Name, name) || strings.EqualFold(p.Namespace.Slug, name) {
+			matches = append(matches, p)
 		}
 	}
+	return matches, nil
+}
 
-	download := selected.Downloads[platform]
+type hangarProjectsResponse struct {
+	Result []hangarProject `json:"result"`
+}
 
-	return &Result{
-		Source:     "hangar",
-		Project:    cfg.Project,
-		Version:    selected.Name,
-		Platform:   platform,
-		URL:        download.DownloadURL,
-		SHA256:     download.FileInfo.SHA256Hash,
-		ResolvedAt: time.Now().UTC(),
-	}, nil
+type hangarProject struct {
+	Name      string `json:"name"`
+	Namespace struct {
+		Owner string `json:"owner"`
+		Slug  string `json:"slug"`
+	} `json:"namespace"`
 }
 
 type hangarVersionsResponse struct {
@@ -93,8 +231,58 @@ type hangarVersionsResponse struct {
 }
 
 type hangarVersion struct {
-	Name      string                       `json:"name"`
-	Downloads map[string]hangarDownload    `json:"downloads"`
+	Name               string                        `json:"name"`
+	Description        string                        `json:"description"`
+	CreatedAt          time.Time                     `json:"createdAt"`
+	Downloads          map[string]hangarDownload     `json:"downloads"`
+	PluginDependencies map[string][]hangarDependency `json:"pluginDependencies"`
+
+	// PlatformDependencies lists the supported platform versions, which
+	// for Paper are Minecraft versions.
+	PlatformDependencies map[string][]string `json:"platformDependencies"`
+}
+
+type hangarDependency struct {
+	Name      string `json:"name"`
+	Required  bool   `json:"required"`
+	Namespace *struct {
+		Owner string `json:"owner"`
+		Slug  string `json:"slug"`
+	} `json:"namespace"`
+	ExternalURL string `json:"externalUrl"`
+}
+
+// dependencies converts a version's dependencies for one platform.
+// Dependencies listed only by name are looked up by searching Hangar.
+// Dependencies hosted elsewhere, or whose project is not found, have no
+// source, only a name and possibly a URL.
+func (h *HangarResolver) dependencies(ctx context.Context, deps []hangarDependency) ([]Dependency, error) {
+	var result []Dependency
+	for _, d := range deps {
+		dep := Dependency{Kind: DependencyOptional, Project: d.Name}
+		if d.Required {
+			dep.Kind = DependencyRequired
+		}
+		switch {
+		case d.Namespace != nil:
+			dep.Source = "hangar"
+			dep.Project = d.Namespace.Owner + "/" + d.Namespace.Slug
+		case d.ExternalURL != "":
+			dep.URL = d.ExternalURL
+		default:
+			projects, err := h.searchProjects(ctx, d.Name)
+			if err != nil {
+				return nil, fmt.Errorf("looking up %s: %w", d.Name, err)
+			}
+			// Several owners with the same name cannot be told apart
+			if len(projects) == 1 {
+				dep.Source = "hangar"
+				dep.Project = projects[0].Namespace.Owner + "/" + projects[0].Namespace.Slug
+			}
+		}
+		result = append(result, dep)
+	}
+	return result, nil
 }
 
 type hangarDownload struct {
@@ -107,28 +295,35 @@ type hangarDownload struct {
 }
 
 func (h *HangarResolver) fetchVersions(ctx context.Context, project string) ([]hangarVersion, error) {
-	url := fmt.Sprintf("%s/projects/%s/versions?limit=100", hangarAPIBase, project)
+	apiURL := fmt.Sprintf("%s/projects/%s/versions?limit=100", hangarAPIBase, project)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
+	var data hangarVersionsResponse
+	if err := h.get(ctx, apiURL, &data); err != nil {
 		return nil, err
 	}
+
+	return data.Result, nil
+}
+
+func (h *HangarResolver) get(ctx context.Context, apiURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
+	if err != nil {
+		return err
+	}
 	req.Header.Set("Accept", "application/json")
 
 	resp, err := h.client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("hangar API returned %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
 	}
-
-	var data hangarVersionsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("hangar API returned %d", resp.StatusCode)
 	}
 
-	return data.Result, nil
+	return json.NewDecoder(resp.Body).Decode(v)
 }
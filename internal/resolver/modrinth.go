@@ -3,9 +3,11 @@ package resolver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -24,29 +26,24 @@ func NewModrinthResolver(client *http.Client) *ModrinthResolver {
 func (m *ModrinthResolver) Name() string { return "modrinth" }
 
 func (m *ModrinthResolver) Resolve(ctx context.Context, cfg PluginConfig) (*Result, error) {
-	loader := cfg.Loader
-	if loader == "" {
-		loader = "velocity"
-	}
-
-	// Fetch versions
-	versions, err := m.fetchVersions(ctx, cfg.Project, loader, cfg.GameVersions)
+	versions, loader, err := m.available(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("fetching versions: %w", err)
+		return nil, err
 	}
 
-	if len(versions) == 0 {
-		return nil, fmt.Errorf("no versions found for %s on %s", cfg.Project, loader)
+	// Extract version strings, skipping versions younger than MinAge
+	var versionStrings []string
+	for _, v := range versions {
+		if !tooNew(v.DatePublished, cfg.MinAge) {
+			versionStrings = append(versionStrings, v.VersionNumber)
+		}
 	}
-
-	// Extract version strings
-	versionStrings := make([]string, len(versions))
-	for i, v := range versions {
-		versionStrings[i] = v.VersionNumber
+	if len(versionStrings) == 0 {
+		return nil, fmt.Errorf("no version of %s is older than %s", cfg.Project, cfg.MinAge)
 	}
 
 	// Select best version based on constraint
-	selectedVersion, err := SelectBestVersion(versionStrings, cfg.Version)
+	selectedVersion, heldBack, err := selectVersion(versionStrings, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("selecting version: %w", err)
 	}
@@ -75,23 +72,253 @@ func (m *ModrinthResolver) Resolve(ctx context.Context, cfg PluginConfig) (*Resu
 		file = selected.Files[0]
 	}
 
+	deps, err := m.dependencies(ctx, selected.Dependencies)
+	if err != nil {
+		return nil, fmt.Errorf("fetching dependencies: %w", err)
+	}
+
+	return &Result{
+		Source:       "modrinth",
+		Project:      cfg.Project,
+		Version:      selected.VersionNumber,
+		Loader:       loader,
+		URL:          file.URL,
+		SHA1:         file.Hashes.SHA1,
+		SHA512:       file.Hashes.SHA512,
+		SHA256:       file.Hashes.SHA256,
+		ResolvedAt:   time.Now().UTC(),
+		PublishedAt:  selected.DatePublished.UTC(),
+		Dependencies: deps,
+		HeldBack:     heldBack,
+	}, nil
+}
+
+// Versions returns the versions available for the configured loader and
+// game versions.
+func (m *ModrinthResolver) Versions(ctx context.Context, cfg PluginConfig) ([]string, error) {
+	available, _, err := m.available(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, len(available))
+	for i, v := range available {
+		versions[i] = v.VersionNumber
+	}
+	return versions, nil
+}
+
+// available fetches the versions matching the configured loader and game
+// versions, newest first.
+func (m *ModrinthResolver) available(ctx context.Context, cfg PluginConfig) ([]modrinthVersion, string, error) {
+	loader := cfg.Loader
+	if loader == "" {
+		loader = "velocity"
+	}
+
+	// Fetch versions
+	versions, err := m.fetchVersions(ctx, cfg.Project, loader, cfg.GameVersions)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetching versions: %w", err)
+	}
+
+	if len(versions) == 0 {
+		return nil, "", fmt.Errorf("no versions found for %s on %s", cfg.Project, loader)
+	}
+
+	return versions, loader, nil
+}
+
+// Notes returns the changelogs of the versions between from and to.
+func (m *ModrinthResolver) Notes(ctx context.Context, cfg PluginConfig, from, to *Result) ([]ReleaseNote, error) {
+	available, _, err := m.available(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, len(available))
+	for i, v := range available {
+		versions[i] = v.VersionNumber
+	}
+
+	var notes []ReleaseNote
+	for _, i := range versionsBetween(versions, fromVersion(from), to.Version) {
+		notes = append(notes, ReleaseNote{Version: available[i].VersionNumber, Text: available[i].Changelog})
+	}
+	return notes, nil
+}
+
+// Identify looks up a local jar by its SHA-512 hash.
+func (m *ModrinthResolver) Identify(ctx context.Context, file LocalFile) (*Result, error) {
+	if file.SHA512 == "" {
+		return nil, nil
+	}
+
+	var version modrinthVersion
+	apiURL := fmt.Sprintf("%s/version_file/%s?algorithm=sha512", modrinthAPIBase, file.SHA512)
+	if err := m.get(ctx, apiURL, &version); err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var project modrinthProject
+	if err := m.get(ctx, fmt.Sprintf("%s/project/%s", modrinthAPIBase, version.ProjectID), &project); err != nil {
+		return nil, fmt.Errorf("fetching project %s: %w", version.ProjectID, err)
+	}
+
+	var matched modrinthFile
+	for _, f := range version.Files {
+		if strings.EqualFold(f.Hashes.SHA512, file.SHA512) {
+			matched = f
+			break
+		}
+	}
+	if matched.URL == "" {
+		return nil, nil
+	}
+
 	return &Result{
 		Source:     "modrinth",
-		Project:    cfg.Project,
-		Version:    selected.VersionNumber,
-		Loader:     loader,
-		URL:        file.URL,
-		SHA512:     file.Hashes.SHA512,
-		SHA256:     file.Hashes.SHA256,
+		Project:    project.Slug,
+		Version:    version.VersionNumber,
+		Loader:     pickLoader(version.Loaders, file.Platform),
+		URL:        matched.URL,
+		SHA512:     matched.Hashes.SHA512,
+		SHA256:     matched.Hashes.SHA256,
 		ResolvedAt: time.Now().UTC(),
 	}, nil
 }
 
+// dependencies converts a version's dependency list, looking up the slugs
+// of the projects and the numbers of pinned versions. Embedded dependencies
+// ship inside the jar and are skipped.
+func (m *ModrinthResolver) dependencies(ctx context.Context, all []modrinthDependency) ([]Dependency, error) {
+	var deps []modrinthDependency
+	for _, d := range all {
+		switch d.DependencyType {
+		case DependencyRequired, DependencyOptional, DependencyIncompatible:
+			deps = append(deps, d)
+		}
+	}
+	if len(deps) == 0 {
+		return nil, nil
+	}
+
+	var versionIDs []string
+	for _, d := range deps {
+		if d.VersionID != "" {
+			versionIDs = append(versionIDs, d.VersionID)
+		}
+	}
+	pinned := make(map[string]modrinthVersion)
+	if len(versionIDs) > 0 {
+		ids, _ := json.Marshal(versionIDs)
+		var versions []modrinthVersion
+		if err := m.get(ctx, modrinthAPIBase+"/versions?ids="+url.QueryEscape(string(ids)), &versions); err != nil {
+			return nil, err
+		}
+		for _, v := range versions {
+			pinned[v.ID] = v
+		}
+	}
+
+	var projectIDs []string
+	for i, d := range deps {
+		if d.ProjectID == "" {
+			deps[i].ProjectID = pinned[d.VersionID].ProjectID
+		}
+		if deps[i].ProjectID != "" {
+			projectIDs = append(projectIDs, deps[i].ProjectID)
+		}
+	}
+	slugs := make(map[string]string)
+	if len(projectIDs) > 0 {
+		ids, _ := json.Marshal(projectIDs)
+		var projects []modrinthProject
+		if err := m.get(ctx, modrinthAPIBase+"/projects?ids="+url.QueryEscape(string(ids)), &projects); err != nil {
+			return nil, err
+		}
+		for _, p := range projects {
+			slugs[p.ID] = p.Slug
+		}
+	}
+
+	// External files and hidden or deleted projects cannot be resolved, which
+	// only matters for required dependencies
+	var result []Dependency
+	for _, d := range deps {
+		slug, ok := slugs[d.ProjectID]
+		if !ok {
+			if d.DependencyType == DependencyRequired {
+				return nil, fmt.Errorf("required dependency %s is not a public Modrinth project", orFileName(d))
+			}
+			continue
+		}
+		result = append(result, Dependency{
+			Kind:    d.DependencyType,
+			Source:  "modrinth",
+			Project: slug,
+			Version: pinned[d.VersionID].VersionNumber,
+		})
+	}
+	return result, nil
+}
+
+// orFileName names a dependency by its project ID, or the file name of an
+// external dependency.
+func orFileName(d modrinthDependency) string {
+	switch {
+	case d.ProjectID != "":
+		return d.ProjectID
+	case d.FileName != "":
+		return d.FileName
+	}
+	return "(unnamed)"
+}
+
+// pickLoader chooses the loader matching the jar's platform.
+func pickLoader(loaders []string, platform string) string {
+	preferred := []string{platform}
+	if platform == "paper" {
+		preferred = append(preferred, "purpur", "spigot", "bukkit")
+	}
+	for _, p := range preferred {
+		for _, l := range loaders {
+			if l == p {
+				return l
+			}
+		}
+	}
+	if len(loaders) > 0 {
+		return loaders[0]
+	}
+	return platform
+}
+
+type modrinthProject struct {
+	ID   string `json:"id"`
+	Slug string `json:"slug"`
+}
+
 type modrinthVersion struct {
-	VersionNumber string         `json:"version_number"`
-	Files         []modrinthFile `json:"files"`
-	Loaders       []string       `json:"loaders"`
-	GameVersions  []string       `json:"game_versions"`
+	ID            string               `json:"id"`
+	ProjectID     string               `json:"project_id"`
+	VersionNumber string               `json:"version_number"`
+	Changelog     string               `json:"changelog"`
+	DatePublished time.Time            `json:"date_published"`
+	Files         []modrinthFile       `json:"files"`
+	Loaders       []string             `json:"loaders"`
+	GameVersions  []string             `json:"game_versions"`
+	Dependencies  []modrinthDependency `json:"dependencies"`
+}
+
+type modrinthDependency struct {
+	VersionID      string `json:"version_id"`
+	ProjectID      string `json:"project_id"`
+	FileName       string `json:"file_name"`
+	DependencyType string `json:"dependency_type"`
 }
 
 type modrinthFile struct {
@@ -100,6 +327,7 @@ type modrinthFile struct {
 	Hashes  struct {
 		SHA512 string `json:"sha512"`
 		SHA256 string `json:"sha256"`
+		SHA1   string `json:"sha1"`
 	} `json:"hashes"`
 }
 
@@ -121,27 +349,34 @@ func (m *ModrinthResolver) fetchVersions(ctx context.Context, project, loader st
 		apiURL += "?" + params.Encode()
 	}
 
+	var versions []modrinthVersion
+	if err := m.get(ctx, apiURL, &versions); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (m *ModrinthResolver) get(ctx context.Context, apiURL string, v interface{}) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	req.Header.Set("Accept", "application/json")
 	req.Header.Set("User-Agent", "scaf/1.0 (github.com/PrimCraft/scaf)")
 
 	resp, err := m.client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("modrinth API returned %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
 	}
-
-	var versions []modrinthVersion
-	if err := json.NewDecoder(resp.Body).Decode(&versions); err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("modrinth API returned %d", resp.StatusCode)
 	}
 
-	return versions, nil
+	return json.NewDecoder(resp.Body).Decode(v)
 }
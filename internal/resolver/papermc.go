@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 	"time"
 )
 
@@ -38,32 +40,90 @@ func (p *PaperMCResolver) Resolve(ctx context.Context, cfg PluginConfig) (*Resul
 		return nil, fmt.Errorf("no versions found for %s", project)
 	}
 
-	// Select version based on constraint
-	selectedVersion, err := SelectBestVersion(versions, cfg.Version)
-	if err != nil {
-		return nil, fmt.Errorf("selecting version: %w", err)
+	// Select version based on constraint. With MinAge, a version whose
+	// builds are all too new is skipped in favor of the next best one.
+	candidates := versions
+	for {
+		selectedVersion, heldBack, err := selectVersion(candidates, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("selecting version: %w", err)
+		}
+		if selectedVersion == "" {
+			if len(candidates) < len(versions) {
+				return nil, fmt.Errorf("no build of %s matching constraint %q is older than %s", project, cfg.Version, cfg.MinAge)
+			}
+			return nil, fmt.Errorf("no version of %s matches constraint %q", project, cfg.Version)
+		}
+
+		// Get latest build for this version
+		build, err := p.fetchLatestBuild(ctx, project, selectedVersion, cfg.MinAge)
+		if err != nil {
+			return nil, fmt.Errorf("fetching build: %w", err)
+		}
+		if build == nil {
+			candidates = slices.DeleteFunc(slices.Clone(candidates), func(v string) bool { return v == selectedVersion })
+			continue
+		}
+
+		downloadURL := fmt.Sprintf("%s/projects/%s/versions/%s/builds/%d/downloads/%s",
+			paperMCAPIBase, project, selectedVersion, build.Build, build.Downloads.Application.Name)
+
+		return &Result{
+			Source:      "papermc",
+			Project:     project,
+			Version:     selectedVersion,
+			Build:       build.Build,
+			URL:         downloadURL,
+			ResolvedAt:  time.Now().UTC(),
+			PublishedAt: build.Time.UTC(),
+			HeldBack:    heldBack,
+		}, nil
 	}
-	if selectedVersion == "" {
-		return nil, fmt.Errorf("no version of %s matches constraint %q", project, cfg.Version)
+}
+
+// Versions returns the versions of the configured project.
+func (p *PaperMCResolver) Versions(ctx context.Context, cfg PluginConfig) ([]string, error) {
+	project := cfg.Project
+	if project == "" {
+		project = "velocity"
 	}
+	return p.fetchVersions(ctx, project)
+}
 
-	// Get latest build for this version
-	build, downloadName, err := p.fetchLatestBuild(ctx, project, selectedVersion)
+// Notes returns the commit summaries of the builds between from and to.
+// Within one version that is the builds after from's; when the version
+// changed, it is every build of the new version up to to's.
+func (p *PaperMCResolver) Notes(ctx context.Context, cfg PluginConfig, from, to *Result) ([]ReleaseNote, error) {
+	project := cfg.Project
+	if project == "" {
+		project = "velocity"
+	}
+
+	builds, err := p.fetchBuilds(ctx, project, to.Version)
 	if err != nil {
-		return nil, fmt.Errorf("fetching build: %w", err)
+		return nil, fmt.Errorf("fetching builds: %w", err)
 	}
 
-	downloadURL := fmt.Sprintf("%s/projects/%s/versions/%s/builds/%d/downloads/%s",
-		paperMCAPIBase, project, selectedVersion, build, downloadName)
+	after := 0
+	if from != nil && from.Version == to.Version {
+		after = from.Build
+	}
 
-	return &Result{
-		Source:     "papermc",
-		Project:    project,
-		Version:    selectedVersion,
-		Build:      build,
-		URL:        downloadURL,
-		ResolvedAt: time.Now().UTC(),
-	}, nil
+	var notes []ReleaseNote
+	for _, b := range builds {
+		if b.Build <= after || b.Build > to.Build {
+			continue
+		}
+		var lines []string
+		for _, c := range b.Changes {
+			lines = append(lines, "- "+c.Summary)
+		}
+		notes = append(notes, ReleaseNote{
+			Version: fmt.Sprintf("%s build %d", to.Version, b.Build),
+			Text:    strings.Join(lines, "\n"),
+		})
+	}
+	return notes, nil
 }
 
 type paperMCProjectResponse struct {
@@ -75,7 +135,13 @@ type paperMCBuildsResponse struct {
 }
 
 type paperMCBuild struct {
-	Build     int `json:"build"`
+	Build   int       `json:"build"`
+	Time    time.Time `json:"time"`
+	Changes []struct {
+		Commit  string `json:"commit"`
+		Summary string `json:"summary"`
+		Message string `json:"message"`
+	} `json:"changes"`
 	Downloads struct {
 		Application struct {
 			Name   string `json:"name"`
@@ -87,24 +153,8 @@ type paperMCBuild struct {
 func (p *PaperMCResolver) fetchVersions(ctx context.Context, project string) ([]string, error) {
 	url := fmt.Sprintf("%s/projects/%s", paperMCAPIBase, project)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Accept", "application/json")
-
-	resp, err := p.client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer func() { _ = resp.Body.Close() }()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("PaperMC API returned %d", resp.StatusCode)
-	}
-
 	var data paperMCProjectResponse
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+	if err := p.get(ctx, url, &data); err != nil {
 		return nil, err
 	}
 
@@ -117,35 +167,58 @@ func (p *PaperMCResolver) fetchVersions(ctx context.Context, project string) ([]
 	return versions, nil
 }
 
-func (p *PaperMCResolver) fetchLatestBuild(ctx context.Context, project, version string) (int, string, error) {
+// fetchBuilds returns all builds of a version, oldest first.
+func (p *PaperMCResolver) fetchBuilds(ctx context.Context, project, version string) ([]paperMCBuild, error) {
 	url := fmt.Sprintf("%s/projects/%s/versions/%s/builds", paperMCAPIBase, project, version)
 
+	var data paperMCBuildsResponse
+	if err := p.get(ctx, url, &data); err != nil {
+		return nil, err
+	}
+
+	return data.Builds, nil
+}
+
+// fetchLatestBuild returns the newest build of a version that is at least
+// minAge old, or nil if every build is newer.
+func (p *PaperMCResolver) fetchLatestBuild(ctx context.Context, project, version string, minAge time.Duration) (*paperMCBuild, error) {
+	builds, err := p.fetchBuilds(ctx, project, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(builds) == 0 {
+		return nil, fmt.Errorf("no builds found for %s %s", project, version)
+	}
+
+	// Latest build is last in the array
+	for i := len(builds) - 1; i >= 0; i-- {
+		if !tooNew(builds[i].Time, minAge) {
+			return &builds[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *PaperMCResolver) get(ctx context.Context, url string, v interface{}) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return 0, "", err
+		return err
 	}
 	req.Header.Set("Accept", "application/json")
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return 0, "", err
+		return err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		return 0, "", fmt.Errorf("PaperMC API returned %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
 	}
-
-	var data paperMCBuildsResponse
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return 0, "", err
-	}
-
-	if len(data.Builds) == 0 {
-		return 0, "", fmt.Errorf("no builds found for %s %s", project, version)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("PaperMC API returned %d", resp.StatusCode)
 	}
 
-	// Latest build is last in the array
-	latest := data.Builds[len(data.Builds)-1]
-	return latest.Build, latest.Downloads.Application.Name, nil
+	return json.NewDecoder(resp.Body).Decode(v)
 }
@@ -0,0 +1,214 @@
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const purpurAPIBase = "https://api.purpurmc.org/v2/purpur"
+
+// PurpurResolver resolves Purpur server builds from the Purpur API.
+type PurpurResolver struct {
+	client *http.Client
+}
+
+// NewPurpurResolver creates a new Purpur resolver.
+func NewPurpurResolver(client *http.Client) *PurpurResolver {
+	return &PurpurResolver{client: client}
+}
+
+func (p *PurpurResolver) Name() string { return "purpur" }
+
+func (p *PurpurResolver) Resolve(ctx context.Context, cfg PluginConfig) (*Result, error) {
+	versions, err := p.fetchVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching versions: %w", err)
+	}
+
+	if len(versions) == 0 {
+		return nil, fmt.Errorf("no versions found for purpur")
+	}
+
+	// As with PaperMC, a version whose builds are all too new is skipped
+	// in favor of the next best one.
+	candidates := versions
+	for {
+		selectedVersion, heldBack, err := selectVersion(candidates, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("selecting version: %w", err)
+		}
+		if selectedVersion == "" {
+			if len(candidates) < len(versions) {
+				return nil, fmt.Errorf("no build of purpur matching constraint %q is older than %s", cfg.Version, cfg.MinAge)
+			}
+			return nil, fmt.Errorf("no version of purpur matches constraint %q", cfg.Version)
+		}
+
+		build, err := p.fetchLatestBuild(ctx, selectedVersion, cfg.MinAge)
+		if err != nil {
+			return nil, fmt.Errorf("fetching build: %w", err)
+		}
+		if build == nil {
+			candidates = slices.DeleteFunc(slices.Clone(candidates), func(v string) bool { return v == selectedVersion })
+			continue
+		}
+
+		number, err := strconv.Atoi(build.Build)
+		if err != nil {
+			return nil, fmt.Errorf("invalid build number %q", build.Build)
+		}
+
+		return &Result{
+			Source:      "purpur",
+			Project:     "purpur",
+			Version:     selectedVersion,
+			Build:       number,
+			URL:         fmt.Sprintf("%s/%s/%s/download", purpurAPIBase, selectedVersion, build.Build),
+			MD5:         build.MD5,
+			ResolvedAt:  time.Now().UTC(),
+			PublishedAt: build.time(),
+			HeldBack:    heldBack,
+		}, nil
+	}
+}
+
+// Versions returns the Minecraft versions Purpur has builds for.
+func (p *PurpurResolver) Versions(ctx context.Context, cfg PluginConfig) ([]string, error) {
+	return p.fetchVersions(ctx)
+}
+
+// Notes returns the commit descriptions of the builds between from and to,
+// in the same way as the PaperMC resolver.
+func (p *PurpurResolver) Notes(ctx context.Context, cfg PluginConfig, from, to *Result) ([]ReleaseNote, error) {
+	builds, err := p.fetchBuilds(ctx, to.Version)
+	if err != nil {
+		return nil, fmt.Errorf("fetching builds: %w", err)
+	}
+
+	after := 0
+	if from != nil && from.Version == to.Version {
+		after = from.Build
+	}
+
+	var notes []ReleaseNote
+	for _, b := range builds {
+		number, err := strconv.Atoi(b.Build)
+		if err != nil || number <= after || number > to.Build {
+			continue
+		}
+		var lines []string
+		for _, c := range b.Commits {
+			summary, _, _ := strings.Cut(c.Description, "\n")
+			lines = append(lines, "- "+summary)
+		}
+		notes = append(notes, ReleaseNote{
+			Version: fmt.Sprintf("%s build %d", to.Version, number),
+			Text:    strings.Join(lines, "\n"),
+		})
+	}
+	return notes, nil
+}
+
+type purpurProjectResponse struct {
+	Versions []string `json:"versions"`
+}
+
+type purpurVersionResponse struct {
+	Builds struct {
+		All []purpurBuild `json:"all"`
+	} `json:"builds"`
+}
+
+type purpurBuild struct {
+	Build     string `json:"build"`
+	MD5       string `json:"md5"`
+	Result    string `json:"result"`
+	Timestamp int64  `json:"timestamp"` // milliseconds
+	Commits   []struct {
+		Hash        string `json:"hash"`
+		Description string `json:"description"`
+	} `json:"commits"`
+}
+
+func (b *purpurBuild) time() time.Time {
+	return time.UnixMilli(b.Timestamp).UTC()
+}
+
+func (p *PurpurResolver) fetchVersions(ctx context.Context) ([]string, error) {
+	var data purpurProjectResponse
+	if err := p.get(ctx, purpurAPIBase, &data); err != nil {
+		return nil, err
+	}
+
+	// Reverse to get newest first (API returns oldest first)
+	versions := data.Versions
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+
+	return versions, nil
+}
+
+// fetchBuilds returns all builds of a version, oldest first.
+func (p *PurpurResolver) fetchBuilds(ctx context.Context, version string) ([]purpurBuild, error) {
+	url := fmt.Sprintf("%s/%s?detailed=true", purpurAPIBase, version)
+
+	var data purpurVersionResponse
+	if err := p.get(ctx, url, &data); err != nil {
+		return nil, err
+	}
+
+	return data.Builds.All, nil
+}
+
+// fetchLatestBuild returns the newest successful build of a version that
+// is at least minAge old, or nil if every build is newer.
+func (p *PurpurResolver) fetchLatestBuild(ctx context.Context, version string, minAge time.Duration) (*purpurBuild, error) {
+	builds, err := p.fetchBuilds(ctx, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(builds) == 0 {
+		return nil, fmt.Errorf("no builds found for purpur %s", version)
+	}
+
+	for i := len(builds) - 1; i >= 0; i-- {
+		if builds[i].Result != "" && builds[i].Result != "SUCCESS" {
+			continue
+		}
+		if !tooNew(builds[i].time(), minAge) {
+			return &builds[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (p *PurpurResolver) get(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Purpur API returned %d", resp.StatusCode)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(v)
+}
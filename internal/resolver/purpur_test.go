@@ -0,0 +1,77 @@
+package resolver
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// testdataTransport answers requests with testdata/<dir>/<last path
+// element>.json, or 404 if there is no such file.
+type testdataTransport string
+
+func (dir testdataTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	data, err := os.ReadFile(filepath.Join("testdata", string(dir), path.Base(req.URL.Path)+".json"))
+	status := http.StatusOK
+	if err != nil {
+		status = http.StatusNotFound
+	}
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(data)),
+		Request:    req,
+	}, nil
+}
+
+func TestPurpurResolve(t *testing.T) {
+	p := NewPurpurResolver(&http.Client{Transport: testdataTransport("purpur")})
+
+	result, err := p.Resolve(context.Background(), PluginConfig{Version: "1.21.4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Version != "1.21.4" || result.Build != 2418 || result.MD5 != "f7b2a4c91d3e5f6a7b8c9d0e1f2a3b4c" {
+		t.Errorf("Resolve() = %s build %d (md5 %s), want 1.21.4 build 2418", result.Version, result.Build, result.MD5)
+	}
+	if want := purpurAPIBase + "/1.21.4/2418/download"; result.URL != want {
+		t.Errorf("URL = %s, want %s", result.URL, want)
+	}
+}
+
+func TestPurpurNotes(t *testing.T) {
+	p := NewPurpurResolver(&http.Client{Transport: testdataTransport("purpur")})
+
+	tests := []struct {
+		name string
+		from *Result
+		want []string
+	}{
+		{"build update", &Result{Version: "1.21.4", Build: 2416}, []string{"1.21.4 build 2417", "1.21.4 build 2418"}},
+		{"added", nil, []string{"1.21.4 build 2416", "1.21.4 build 2417", "1.21.4 build 2418"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notes, err := p.Notes(context.Background(), PluginConfig{}, tt.from, &Result{Version: "1.21.4", Build: 2418})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, n := range notes {
+				got = append(got, n.Version)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("Notes() = %q, want %q", got, tt.want)
+			}
+			if last := notes[len(notes)-1].Text; last != "- Updated Upstream (Paper)" {
+				t.Errorf("last note = %q", last)
+			}
+		})
+	}
+}
@@ -3,11 +3,19 @@ package resolver
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"time"
 )
 
+// errNotFound is returned by API helpers when the upstream responds 404.
+var errNotFound = errors.New("not found")
+
+// ErrUnsupported is returned when a source does not support an operation.
+var ErrUnsupported = errors.New("not supported by source")
+
 // Result is the resolved plugin information.
 type Result struct {
 	Source     string    `yaml:"source"`
@@ -18,9 +26,48 @@ type Result struct {
 	Loader     string    `yaml:"loader,omitempty"`
 	URL        string    `yaml:"url,omitempty"`
 	S3URI      string    `yaml:"s3_uri,omitempty"`
+	MD5        string    `yaml:"md5,omitempty"`
+	SHA1       string    `yaml:"sha1,omitempty"`
 	SHA256     string    `yaml:"sha256,omitempty"`
 	SHA512     string    `yaml:"sha512,omitempty"`
 	ResolvedAt time.Time `yaml:"resolved_at"`
+
+	// LoaderVersion is the Fabric, NeoForge or Forge version of a modded
+	// server, and InstallerVersion the Fabric installer it is built with.
+	LoaderVersion    string `yaml:"loader_version,omitempty"`
+	InstallerVersion string `yaml:"installer_version,omitempty"`
+
+	// PublishedAt is when the selected version or build was published
+	// upstream, if the source reports it.
+	PublishedAt time.Time `yaml:"published_at,omitempty"`
+
+	// Dependencies are the dependencies declared by the selected version.
+	Dependencies []Dependency `yaml:"-"`
+
+	// HeldBack is the version that would have been selected without the
+	// MaxBump policy, if the policy changed the selection.
+	HeldBack string `yaml:"-"`
+}
+
+// Dependency kinds.
+const (
+	DependencyRequired     = "required"
+	DependencyOptional     = "optional"
+	DependencyIncompatible = "incompatible"
+)
+
+// Dependency is another plugin that a resolved version depends on or
+// conflicts with.
+type Dependency struct {
+	Kind string
+	// Source and Project identify the dependency in the same form as a
+	// manifest entry. Dependencies that no source hosts have no Source, a
+	// plugin name as Project and a URL to get them from, if known.
+	Source  string
+	Project string
+	URL     string
+	// Version pins a specific version, if the dependency names one.
+	Version string
 }
 
 // PluginConfig is the input configuration from the manifest.
@@ -34,6 +81,25 @@ type PluginConfig struct {
 	Bucket       string   `yaml:"bucket,omitempty"`
 	Key          string   `yaml:"key,omitempty"`
 	URL          string   `yaml:"url,omitempty"`
+
+	// LoaderVersion constrains the loader of a Fabric, NeoForge or Forge
+	// server, and InstallerVersion the Fabric installer.
+	LoaderVersion    string `yaml:"loader_version,omitempty"`
+	InstallerVersion string `yaml:"installer_version,omitempty"`
+
+	// Current is the currently locked version, if any.
+	Current string `yaml:"-"`
+	// MaxBump limits how far the selection may move from Current:
+	// "major" or "" (no limit), "minor" or "patch".
+	MaxBump string `yaml:"-"`
+	// MinAge skips versions published less than this long ago.
+	MinAge time.Duration `yaml:"-"`
+}
+
+// tooNew reports whether a version published at t is younger than minAge.
+// Versions without a publish time are never too new.
+func tooNew(t time.Time, minAge time.Duration) bool {
+	return minAge > 0 && !t.IsZero() && time.Since(t) < minAge
 }
 
 // Resolver resolves a plugin from a specific source.
@@ -44,9 +110,54 @@ type Resolver interface {
 	Resolve(ctx context.Context, cfg PluginConfig) (*Result, error)
 }
 
+// Lister is implemented by resolvers that can enumerate available versions.
+type Lister interface {
+	// Versions returns all versions available for the config, newest first.
+	Versions(ctx context.Context, cfg PluginConfig) ([]string, error)
+}
+
+// ReleaseNote is the changelog of a single upstream version or build.
+type ReleaseNote struct {
+	Version string `json:"version"`
+	Text    string `json:"text"`
+}
+
+// NoteFetcher is implemented by resolvers that can fetch upstream release
+// notes.
+type NoteFetcher interface {
+	// Notes returns the notes of every version after from, up to and
+	// including to, oldest first. from is nil for newly added entries.
+	Notes(ctx context.Context, cfg PluginConfig, from, to *Result) ([]ReleaseNote, error)
+}
+
+func fromVersion(from *Result) string {
+	if from == nil {
+		return ""
+	}
+	return from.Version
+}
+
+// LocalFile describes a plugin jar found on disk.
+type LocalFile struct {
+	Name     string // Declared plugin name
+	Version  string // Declared plugin version
+	Platform string // Plugin platform ("paper" or "velocity")
+	SHA1     string
+	SHA256   string
+	SHA512   string
+}
+
+// Identifier is implemented by resolvers that can map a local jar back to
+// its upstream project and version.
+type Identifier interface {
+	// Identify returns the matching result, or nil if the file is unknown.
+	Identify(ctx context.Context, file LocalFile) (*Result, error)
+}
+
 // Registry holds all available resolvers.
 type Registry struct {
 	resolvers map[string]Resolver
+	order     []string
 	client    *http.Client
 }
 
@@ -65,6 +176,10 @@ func NewRegistry() *Registry {
 	r.Register(NewHangarResolver(client))
 	r.Register(NewModrinthResolver(client))
 	r.Register(NewPaperMCResolver(client))
+	r.Register(NewPurpurResolver(client))
+	r.Register(NewFabricResolver(client))
+	r.Register(NewNeoForgeResolver(client))
+	r.Register(NewForgeResolver(client))
 	r.Register(NewS3Resolver())
 	r.Register(NewURLResolver())
 
@@ -73,6 +188,9 @@ func NewRegistry() *Registry {
 
 // Register adds a resolver to the registry.
 func (r *Registry) Register(res Resolver) {
+	if _, ok := r.resolvers[res.Name()]; !ok {
+		r.order = append(r.order, res.Name())
+	}
 	r.resolvers[res.Name()] = res
 }
 
@@ -91,11 +209,66 @@ func (r *Registry) Resolve(ctx context.Context, source string, cfg PluginConfig)
 	return resolver.Resolve(ctx, cfg)
 }
 
+// Versions lists the available versions using the appropriate resolver.
+// It returns ErrUnsupported if the source cannot enumerate versions.
+func (r *Registry) Versions(ctx context.Context, source string, cfg PluginConfig) ([]string, error) {
+	res, ok := r.Get(source)
+	if !ok {
+		return nil, fmt.Errorf("unknown source: %s", source)
+	}
+	lister, ok := res.(Lister)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return lister.Versions(ctx, cfg)
+}
+
+// Notes fetches release notes using the appropriate resolver. It returns
+// ErrUnsupported if the source has no release notes.
+func (r *Registry) Notes(ctx context.Context, source string, cfg PluginConfig, from, to *Result) ([]ReleaseNote, error) {
+	res, ok := r.Get(source)
+	if !ok {
+		return nil, fmt.Errorf("unknown source: %s", source)
+	}
+	fetcher, ok := res.(NoteFetcher)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	return fetcher.Notes(ctx, cfg, from, to)
+}
+
+// identifyFirst are the sources Identify asks before the others, which
+// follow in registration order. Modrinth looks files up by exact hash.
+var identifyFirst = []string{"modrinth"}
+
+// Identify asks each resolver that supports it to identify a local file,
+// Modrinth first. It returns nil if no source recognizes it.
+func (r *Registry) Identify(ctx context.Context, file LocalFile) (*Result, error) {
+	order := slices.Clone(identifyFirst)
+	for _, name := range r.order {
+		if !slices.Contains(order, name) {
+			order = append(order, name)
+		}
+	}
+	for _, name := range order {
+		identifier, ok := r.resolvers[name].(Identifier)
+		if !ok {
+			continue
+		}
+		result, err := identifier.Identify(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if result != nil {
+			return result, nil
+		}
+	}
+	return nil, nil
+}
+
 // Sources returns all registered source names.
 func (r *Registry) Sources() []string {
-	names := make([]string, 0, len(r.resolvers))
-	for name := range r.resolvers {
-		names = append(names, name)
-	}
+	names := make([]string, len(r.order))
+	copy(names, r.order)
 	return names
 }
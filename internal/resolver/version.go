@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"fmt"
 	"regexp"
 	"sort"
 	"strings"
@@ -103,6 +104,13 @@ func SelectBestVersion(versions []string, constraint string) (string, error) {
 		return versions[0], nil
 	}
 
+	// Exact pins also work for versions that are not valid semver
+	for _, v := range versions {
+		if v == constraint {
+			return v, nil
+		}
+	}
+
 	filtered, err := FilterVersions(versions, constraint)
 	if err != nil {
 		return "", err
@@ -112,3 +120,164 @@ func SelectBestVersion(versions []string, constraint string) (string, error) {
 	}
 	return filtered[0], nil
 }
+
+// selectVersion selects the best version for cfg, honoring its MaxBump
+// policy. If the policy prevented a newer version from being selected,
+// that version is returned as heldBack.
+func selectVersion(versions []string, cfg PluginConfig) (selected, heldBack string, err error) {
+	best, err := SelectBestVersion(versions, cfg.Version)
+	if err != nil || best == "" || cfg.Current == "" || WithinBump(cfg.Current, best, cfg.MaxBump) {
+		return best, "", err
+	}
+
+	var allowed []string
+	for _, v := range versions {
+		if WithinBump(cfg.Current, v, cfg.MaxBump) {
+			allowed = append(allowed, v)
+		}
+	}
+
+	selected, err = SelectBestVersion(allowed, cfg.Version)
+	if err != nil {
+		return "", "", err
+	}
+	if selected == "" {
+		return "", "", fmt.Errorf("no version within max_bump %s of %s matches constraint %q (newest is %s)",
+			cfg.MaxBump, cfg.Current, cfg.Version, best)
+	}
+	return selected, best, nil
+}
+
+// WithinBump reports whether moving from current to version stays within
+// maxBump: "major" or "" (any change), "minor" (same major version) or
+// "patch" (same major and minor version). Downgrades are allowed. If either
+// version cannot be parsed, the size of the change is unknown, so only
+// staying on current is allowed.
+func WithinBump(current, version, maxBump string) bool {
+	if maxBump == "" || maxBump == "major" || version == current {
+		return true
+	}
+
+	c, err := ParseVersion(current)
+	if err != nil {
+		return false
+	}
+	v, err := ParseVersion(version)
+	if err != nil {
+		return false
+	}
+	if v.LessThan(c) {
+		return true
+	}
+
+	switch maxBump {
+	case "minor":
+		return v.Major() == c.Major()
+	case "patch":
+		return v.Major() == c.Major() && v.Minor() == c.Minor()
+	}
+	return true
+}
+
+// Satisfies reports whether version matches the constraint. Exact string
+// matches always satisfy, so non-semver pins keep working.
+func Satisfies(version, constraint string) bool {
+	if constraint == "" || constraint == "latest" || version == constraint {
+		return true
+	}
+
+	c, err := ParseConstraint(constraint)
+	if err != nil || c == nil {
+		return false
+	}
+	v, err := ParseVersion(version)
+	if err != nil {
+		return false
+	}
+	return c.Check(v)
+}
+
+// CompareVersions compares two versions, returning -1, 0 or 1. The result
+// is only meaningful if ok is true, i.e. both versions could be parsed.
+func CompareVersions(a, b string) (cmp int, ok bool) {
+	va, err := ParseVersion(a)
+	if err != nil {
+		return 0, false
+	}
+	vb, err := ParseVersion(b)
+	if err != nil {
+		return 0, false
+	}
+	return va.Compare(vb), true
+}
+
+// Bump classifies the change between two versions as "major", "minor",
+// "patch" or "prerelease". It returns "" if the versions are equal and
+// "unknown" if either cannot be parsed.
+func Bump(from, to string) string {
+	if from == to {
+		return ""
+	}
+
+	a, err := ParseVersion(from)
+	if err != nil {
+		return "unknown"
+	}
+	b, err := ParseVersion(to)
+	if err != nil {
+		return "unknown"
+	}
+
+	switch {
+	case a.Major() != b.Major():
+		return "major"
+	case a.Minor() != b.Minor():
+		return "minor"
+	case a.Patch() != b.Patch():
+		return "patch"
+	case a.Prerelease() != b.Prerelease():
+		return "prerelease"
+	}
+	return ""
+}
+
+// versionsBetween returns the indexes of the versions after from, up to and
+// including to, in a newest-first list, ordered oldest first. Versions are
+// compared as semver when possible, otherwise by their position in the list.
+// An empty from selects only to.
+func versionsBetween(versions []string, from, to string) []int {
+	if from == "" {
+		for i, v := range versions {
+			if v == to {
+				return []int{i}
+			}
+		}
+		return nil
+	}
+
+	var idx []int
+	if _, ok := CompareVersions(from, to); ok {
+		for i := len(versions) - 1; i >= 0; i-- {
+			afterFrom, ok1 := CompareVersions(versions[i], from)
+			uptoTo, ok2 := CompareVersions(versions[i], to)
+			if ok1 && ok2 && afterFrom > 0 && uptoTo <= 0 {
+				idx = append(idx, i)
+			}
+		}
+		return idx
+	}
+
+	start := -1
+	for i, v := range versions {
+		if v == from {
+			break
+		}
+		if v == to {
+			start = i
+		}
+		if start >= 0 {
+			idx = append([]int{i}, idx...)
+		}
+	}
+	return idx
+}
@@ -0,0 +1,110 @@
+package resolver
+
+import "testing"
+
+func TestWithinBump(t *testing.T) {
+	tests := []struct {
+		current, version, maxBump string
+		want                      bool
+	}{
+		{"1.2.3", "9.0.0", "", true},
+		{"1.2.3", "9.0.0", "major", true},
+		{"1.2.3", "1.9.0", "minor", true},
+		{"1.2.3", "2.0.0", "minor", false},
+		{"1.2.3", "1.2.9", "patch", true},
+		{"1.2.3", "1.3.0", "patch", false},
+		{"2.0.0", "1.0.0", "patch", true},
+
+		// Prereleases count as the version they lead up to
+		{"1.2.3", "1.2.4-SNAPSHOT", "patch", true},
+		{"1.2.3", "1.3.0-beta.1", "patch", false},
+		{"1.3.0-beta.1", "1.3.0", "patch", true},
+		{"1.2.3", "2.0.0-rc.1", "minor", false},
+
+		// Non-semver versions only allow staying on current
+		{"build-45", "build-45", "patch", true},
+		{"build-45", "build-46", "patch", false},
+		{"1.2.3", "latest-dev", "minor", false},
+		{"dev", "1.2.3", "minor", false},
+		{"build-45", "build-46", "major", true},
+	}
+	for _, tt := range tests {
+		if got := WithinBump(tt.current, tt.version, tt.maxBump); got != tt.want {
+			t.Errorf("WithinBump(%q, %q, %q) = %v, want %v", tt.current, tt.version, tt.maxBump, got, tt.want)
+		}
+	}
+}
+
+func TestSelectVersion(t *testing.T) {
+	tests := []struct {
+		name       string
+		versions   []string // newest first
+		cfg        PluginConfig
+		want, held string
+		wantErr    bool
+	}{
+		{
+			name:     "no baseline",
+			versions: []string{"2.0.0", "1.3.0", "1.2.5", "1.2.4"},
+			cfg:      PluginConfig{Version: "latest", MaxBump: "patch"},
+			want:     "2.0.0",
+		},
+		{
+			name:     "patch",
+			versions: []string{"2.0.0", "1.3.0", "1.2.5", "1.2.4"},
+			cfg:      PluginConfig{Version: "latest", Current: "1.2.4", MaxBump: "patch"},
+			want:     "1.2.5",
+			held:     "2.0.0",
+		},
+		{
+			name:     "minor",
+			versions: []string{"2.0.0", "1.3.0", "1.2.5", "1.2.4"},
+			cfg:      PluginConfig{Version: "latest", Current: "1.2.4", MaxBump: "minor"},
+			want:     "1.3.0",
+			held:     "2.0.0",
+		},
+		{
+			name:     "major",
+			versions: []string{"2.0.0", "1.3.0", "1.2.5", "1.2.4"},
+			cfg:      PluginConfig{Version: "latest", Current: "1.2.4", MaxBump: "major"},
+			want:     "2.0.0",
+		},
+		{
+			name:     "constraint within policy",
+			versions: []string{"1.3.0", "1.2.5", "1.2.4"},
+			cfg:      PluginConfig{Version: "~1.2", Current: "1.2.4", MaxBump: "patch"},
+			want:     "1.2.5",
+		},
+		{
+			name:     "prerelease",
+			versions: []string{"1.3.0-beta.1", "1.2.5-SNAPSHOT", "1.2.4"},
+			cfg:      PluginConfig{Version: "latest", Current: "1.2.4", MaxBump: "patch"},
+			want:     "1.2.5-SNAPSHOT",
+			held:     "1.3.0-beta.1",
+		},
+		{
+			name:     "non-semver stays on current",
+			versions: []string{"b47", "b46", "b45"},
+			cfg:      PluginConfig{Version: "latest", Current: "b45", MaxBump: "patch"},
+			want:     "b45",
+			held:     "b47",
+		},
+		{
+			name:     "non-semver current gone",
+			versions: []string{"b47", "b46"},
+			cfg:      PluginConfig{Version: "latest", Current: "b45", MaxBump: "minor"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, held, err := selectVersion(tt.versions, tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want || held != tt.held {
+				t.Errorf("selectVersion() = %q, %q, want %q, %q", got, held, tt.want, tt.held)
+			}
+		})
+	}
+}
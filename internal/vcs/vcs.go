@@ -0,0 +1,88 @@
+// Package vcs reads files from git revisions without shelling out to git.
+package vcs
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ReadFile returns the contents of path at the given revision of the git
+// repository containing the current directory. rev accepts anything
+// go-git can resolve, such as HEAD~1, origin/main, tags or commit hashes.
+//
+// As with "git show rev:path", path is relative to the repository root,
+// unless it is absolute or starts with "./" or "../", in which case it is
+// relative to the current directory.
+func ReadFile(rev, path string) ([]byte, error) {
+	repo, err := openRepo()
+	if err != nil {
+		return nil, err
+	}
+
+	if filepath.IsAbs(path) || strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../") {
+		if path, err = repoPath(repo, path); err != nil {
+			return nil, err
+		}
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(rev))
+	if err != nil {
+		return nil, fmt.Errorf("resolving %s: %w", rev, err)
+	}
+	commit, err := repo.CommitObject(*hash)
+	if err != nil {
+		return nil, fmt.Errorf("reading commit %s: %w", rev, err)
+	}
+	file, err := commit.File(filepath.ToSlash(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s at %s: %w", path, rev, err)
+	}
+
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(contents), nil
+}
+
+// RepoPath returns path, relative to the current directory or absolute, as
+// a path relative to the root of the repository containing the current
+// directory.
+func RepoPath(path string) (string, error) {
+	repo, err := openRepo()
+	if err != nil {
+		return "", err
+	}
+	return repoPath(repo, path)
+}
+
+func openRepo() (*git.Repository, error) {
+	repo, err := git.PlainOpenWithOptions(".", &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, fmt.Errorf("opening git repository: %w", err)
+	}
+	return repo, nil
+}
+
+func repoPath(repo *git.Repository, path string) (string, error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(wt.Filesystem.Root(), abs)
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsLocal(rel) {
+		return "", fmt.Errorf("%s is outside the repository", path)
+	}
+	return filepath.ToSlash(rel), nil
+}